@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+)
+
+// ErrUnreachable is returned by the client when nothing answers on its
+// address, usually because no timer was started with --listen.
+var ErrUnreachable = errors.New("no pomo api is listening")
+
+// Client drives a running timer through its api.
+type Client struct {
+	base string
+	http *http.Client
+}
+
+func NewClient(addr string) *Client {
+	base := addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return &Client{
+		base: strings.TrimSuffix(base, "/"),
+		http: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (c *Client) Timer() (*events.TimerState, error) {
+	var state events.TimerState
+	if err := c.do(http.MethodGet, "/api/timer", nil, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Start asks a server that runs timers, pomo serve, to start one for the
+// current session.
+func (c *Client) Start() (*events.TimerState, error) {
+	var state events.TimerState
+	if err := c.do(http.MethodPost, "/api/timer/start", nil, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (c *Client) Extend(minutes int) (*events.TimerState, error) {
+	var state events.TimerState
+	if err := c.do(http.MethodPost, "/api/timer/extend", extendRequest{Minutes: minutes}, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (c *Client) Skip() error {
+	return c.do(http.MethodPost, "/api/timer/skip", nil, nil)
+}
+
+func (c *Client) Snooze() error {
+	return c.do(http.MethodPost, "/api/timer/snooze", nil, nil)
+}
+
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var in io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		in = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, in)
+	if err != nil {
+		return err
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w on %s (%s)", ErrUnreachable, c.base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return errors.New(e.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
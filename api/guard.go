@@ -0,0 +1,70 @@
+package api
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// guard keeps other web pages away from the api. Requests must name the
+// server by localhost or a loopback address, which stops dns rebinding, and
+// a browser sending a request from another origin is turned away. Requests
+// with a body that change something must be json, which a page cannot send
+// to another origin without a preflight the api never answers.
+func guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !localHost(r.Host) {
+			writeError(w, &statusError{status: http.StatusForbidden, msg: "host not allowed"})
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			writeError(w, &statusError{status: http.StatusForbidden, msg: "cross-origin requests are not allowed"})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if !hasBody(r) {
+				break
+			}
+			media, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || media != "application/json" {
+				writeError(w, &statusError{status: http.StatusUnsupportedMediaType, msg: "requests must be application/json"})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// localHost reports whether host, as sent in a request, is localhost or a
+// loopback address rather than a name someone else's dns could point here.
+func localHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hasBody reports whether a request came with a body, of known length or
+// chunked.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength != 0
+}
+
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, host)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func do(t *testing.T, method, url, contentType, body string, header http.Header) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body == "" {
+		req.Body = http.NoBody
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if host := header.Get("Host"); host != "" {
+		req.Host = host
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestGuard(t *testing.T) {
+	web, store := newTestServer(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		header      http.Header
+		want        int
+	}{
+		{name: "post without a body", method: http.MethodPost, path: "/complete", want: http.StatusOK},
+		{name: "delete without a body", method: http.MethodDelete, want: http.StatusNoContent},
+		{name: "json body", method: http.MethodPatch, contentType: "application/json", body: `{"title":"renamed"}`, want: http.StatusOK},
+		{name: "form body", method: http.MethodPatch, contentType: "application/x-www-form-urlencoded", body: "title=renamed", want: http.StatusUnsupportedMediaType},
+		{name: "body without a type", method: http.MethodPatch, body: `{"title":"renamed"}`, want: http.StatusUnsupportedMediaType},
+		{name: "localhost", method: http.MethodGet, header: http.Header{"Host": {"localhost:7070"}}, want: http.StatusOK},
+		{name: "ipv6 loopback", method: http.MethodGet, header: http.Header{"Host": {"[::1]:7070"}}, want: http.StatusOK},
+		{name: "lan address", method: http.MethodGet, header: http.Header{"Host": {"192.168.1.20:7070"}}, want: http.StatusForbidden},
+		{name: "rebound name", method: http.MethodGet, header: http.Header{"Host": {"evil.example:7070"}}, want: http.StatusForbidden},
+		{name: "other origin", method: http.MethodGet, header: http.Header{"Origin": {"http://evil.example"}}, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, err := store.Add("write tests")
+			if err != nil {
+				t.Fatal(err)
+			}
+			url := web.URL + "/api/tasks/" + added.ID.String() + tt.path
+
+			if got := do(t, tt.method, url, tt.contentType, tt.body, tt.header); got != tt.want {
+				t.Errorf("%s answered %d, want %d", tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenOnlyOnLoopback(t *testing.T) {
+	l, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	if l, err := Listen("0.0.0.0:0"); err == nil {
+		l.Close()
+		t.Error("listened on every interface")
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/google/uuid"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// handleMetrics renders metrics in the prometheus text exposition format.
+// Completed sessions, focus time and interruptions are derived from the
+// recorded history on every scrape, so they survive restarts like real
+// counters would. Abandoned and skipped sessions are recorded too, they
+// only count towards interruptions.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	history, err := s.sessionStore.History()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	tasks, err := s.store.List(func(t task.Task) bool {
+		return true
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	projects := make(map[uuid.UUID]string, len(tasks))
+	pending := 0
+	for _, t := range tasks {
+		if len(t.Projects) > 0 {
+			projects[t.ID] = t.Projects[0]
+		}
+		if t.Status == task.Pending {
+			pending++
+		}
+	}
+
+	type series struct {
+		sessionType sessions.Type
+		project     string
+	}
+
+	completed := make(map[series]int)
+	var focus float64
+	interruptions := 0
+	for _, record := range history {
+		interruptions += len(record.Interruptions)
+		if record.Abandoned || record.Skipped {
+			continue
+		}
+		completed[series{record.Type, projects[record.TaskID]}]++
+		if record.Type == sessions.Focus {
+			focus += record.Duration.Seconds()
+		}
+	}
+
+	keys := make([]series, 0, len(completed))
+	for k := range completed {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].sessionType != keys[j].sessionType {
+			return keys[i].sessionType < keys[j].sessionType
+		}
+		return keys[i].project < keys[j].project
+	})
+
+	var remaining float64
+	if timer := s.attached(); timer != nil {
+		remaining = timer.State().Remaining.Seconds()
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+
+	header(w, "pomo_sessions_completed_total", "counter", "Sessions run to completion.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "pomo_sessions_completed_total{type=%s,project=%s} %d\n", label(string(k.sessionType)), label(k.project), completed[k])
+	}
+
+	header(w, "pomo_focus_seconds_total", "counter", "Time spent in completed focus sessions.")
+	fmt.Fprintf(w, "pomo_focus_seconds_total %g\n", focus)
+
+	header(w, "pomo_interruptions_total", "counter", "Interruptions logged during recorded sessions.")
+	fmt.Fprintf(w, "pomo_interruptions_total %d\n", interruptions)
+
+	header(w, "pomo_timer_remaining_seconds", "gauge", "Time left on the running timer, 0 when idle.")
+	fmt.Fprintf(w, "pomo_timer_remaining_seconds %g\n", remaining)
+
+	header(w, "pomo_pending_tasks", "gauge", "Tasks that are not complete.")
+	fmt.Fprintf(w, "pomo_pending_tasks %d\n", pending)
+}
+
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func label(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+)
+
+const DefaultListen = "127.0.0.1:7070"
+
+// Timer is a running timer, which the api can extend or, during a break,
+// skip.
+type Timer interface {
+	State() events.TimerState
+	Extend(d time.Duration) error
+	Skip() error
+}
+
+// Alarm is a finished session waiting for the next one to start, which
+// can be put off for a while.
+type Alarm interface {
+	Snooze() error
+}
+
+// Launcher starts a timer for the current session, which attaches itself
+// to the server while it runs.
+type Launcher interface {
+	Launch() error
+}
+
+type Server struct {
+	store        task.Store
+	sessionStore sessions.Store
+	intervals    int
+	timerMu      sync.RWMutex
+	timer        Timer
+	alarm        Alarm
+	launcher     Launcher
+	bus          *events.Bus
+	mux          *http.ServeMux
+}
+
+func NewServer(store task.Store, sessionStore sessions.Store, intervals int, bus *events.Bus) *Server {
+	if bus == nil {
+		bus = events.NewBus()
+	}
+
+	s := &Server{
+		store:        store,
+		sessionStore: sessionStore,
+		intervals:    intervals,
+		bus:          bus,
+		mux:          http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/api/tasks", s.handleTasks)
+	s.mux.HandleFunc("/api/tasks/", s.handleTask)
+	s.mux.HandleFunc("/api/current", s.handleCurrent)
+	s.mux.HandleFunc("/api/session", s.handleSession)
+	s.mux.HandleFunc("/api/session/reset", s.handleSessionReset)
+	s.mux.HandleFunc("/api/timer", s.handleTimer)
+	s.mux.HandleFunc("/api/timer/start", s.handleStart)
+	s.mux.HandleFunc("/api/timer/extend", s.handleExtend)
+	s.mux.HandleFunc("/api/timer/skip", s.handleSkip)
+	s.mux.HandleFunc("/api/timer/snooze", s.handleSnooze)
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	s.mux.HandleFunc("/api/history", s.handleHistory)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.Handle("/", dashboard())
+	return s
+}
+
+// Attach exposes a running timer through /api/timer, replacing any timer
+// attached before. Attaching nil reports that no timer is running.
+func (s *Server) Attach(timer Timer) {
+	s.timerMu.Lock()
+	defer s.timerMu.Unlock()
+	s.timer = timer
+}
+
+// Launch lets /api/timer/start run timers with l.
+func (s *Server) Launch(l Launcher) {
+	s.timerMu.Lock()
+	defer s.timerMu.Unlock()
+	s.launcher = l
+}
+
+func (s *Server) launching() Launcher {
+	s.timerMu.RLock()
+	defer s.timerMu.RUnlock()
+	return s.launcher
+}
+
+func (s *Server) attached() Timer {
+	s.timerMu.RLock()
+	defer s.timerMu.RUnlock()
+	return s.timer
+}
+
+// Ring exposes the alarm of a finished session through /api/timer/snooze.
+// Ringing nil reports that nothing is waiting.
+func (s *Server) Ring(alarm Alarm) {
+	s.timerMu.Lock()
+	defer s.timerMu.Unlock()
+	s.alarm = alarm
+}
+
+func (s *Server) ringing() Alarm {
+	s.timerMu.RLock()
+	defer s.timerMu.RUnlock()
+	return s.alarm
+}
+
+func (s *Server) Handler() http.Handler {
+	return guard(s.mux)
+}
+
+// Listen listens on addr, which must be a loopback address: the api has no
+// authentication, so it is never offered to the network.
+func Listen(addr string) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if tcp, ok := l.Addr().(*net.TCPAddr); !ok || !tcp.IP.IsLoopback() {
+		l.Close()
+		return nil, fmt.Errorf("refusing to serve the api on %s, it has no authentication so it only listens on loopback addresses such as %s", addr, DefaultListen)
+	}
+	return l, nil
+}
+
+// Serve handles requests on l until ctx is done, then shuts down gracefully.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *statusError
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		status = http.StatusNotFound
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func conflict(msg string) error {
+	return &statusError{status: http.StatusConflict, msg: msg}
+}
+
+func badRequest(msg string) error {
+	return &statusError{status: http.StatusBadRequest, msg: msg}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	for _, m := range allowed {
+		w.Header().Add("Allow", m)
+	}
+	writeError(w, &statusError{status: http.StatusMethodNotAllowed, msg: "method not allowed"})
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+)
+
+type sessionResponse struct {
+	Current sessions.Type `json:"current"`
+	Next    sessions.Type `json:"next"`
+	Count   int           `json:"count"`
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	s.writeSession(w)
+}
+
+func (s *Server) handleSessionReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	if err := s.sessionStore.Reset(); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeSession(w)
+}
+
+func (s *Server) writeSession(w http.ResponseWriter) {
+	session, err := s.sessionStore.Session()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessionResponse{
+		Current: session.Current,
+		Next:    session.Next(s.intervals),
+		Count:   session.Count,
+	})
+}
+
+func (s *Server) handleTimer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	timer := s.attached()
+	if timer == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no timer running"})
+		return
+	}
+	writeJSON(w, http.StatusOK, timer.State())
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	launcher := s.launching()
+	if launcher == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "this server does not start timers"})
+		return
+	}
+	if s.attached() != nil {
+		writeError(w, conflict("a timer is already running"))
+		return
+	}
+	if err := launcher.Launch(); err != nil {
+		writeError(w, conflict(err.Error()))
+		return
+	}
+
+	timer := s.attached()
+	if timer == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, timer.State())
+}
+
+type extendRequest struct {
+	Minutes int `json:"minutes"`
+}
+
+func (s *Server) handleExtend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var req extendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, badRequest(err.Error()))
+		return
+	}
+	if req.Minutes < 1 {
+		writeError(w, badRequest("minutes must be a positive number"))
+		return
+	}
+
+	timer := s.attached()
+	if timer == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no timer running"})
+		return
+	}
+	if err := timer.Extend(time.Duration(req.Minutes) * time.Minute); err != nil {
+		writeError(w, conflict(err.Error()))
+		return
+	}
+	writeJSON(w, http.StatusOK, timer.State())
+}
+
+func (s *Server) handleSkip(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	timer := s.attached()
+	if timer == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no timer running"})
+		return
+	}
+	if err := timer.Skip(); err != nil {
+		writeError(w, conflict(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSnooze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	alarm := s.ringing()
+	if alarm == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no finished session to snooze"})
+		return
+	}
+	if err := alarm.Snooze(); err != nil {
+		writeError(w, conflict(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	history, err := s.sessionStore.History()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if value := r.URL.Query().Get("days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 {
+			writeError(w, badRequest("days must be a positive number"))
+			return
+		}
+
+		since := time.Now().AddDate(0, 0, -days)
+		recent := make([]sessions.Record, 0, len(history))
+		for _, record := range history {
+			if record.StartedAt.After(since) {
+				recent = append(recent, record)
+			}
+		}
+		history = recent
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/task"
+	"github.com/google/uuid"
+)
+
+type addTaskRequest struct {
+	Title string `json:"title"`
+}
+
+type updateTaskRequest struct {
+	Title    *string      `json:"title"`
+	Status   *task.Status `json:"status"`
+	Priority *string      `json:"priority"`
+	Projects []string     `json:"projects"`
+	Contexts []string     `json:"contexts"`
+	Tags     []string     `json:"tags"`
+}
+
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		all := r.URL.Query().Get("all") == "true"
+		tasks, err := s.store.List(func(t task.Task) bool {
+			return all || t.Status != task.Complete
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, tasks)
+	case http.MethodPost:
+		var req addTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, badRequest(err.Error()))
+			return
+		}
+		if strings.TrimSpace(req.Title) == "" {
+			writeError(w, badRequest("title is required"))
+			return
+		}
+
+		t, err := s.store.Add(req.Title)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.taskChanged(t)
+		s.bus.Publish(events.Event{Type: events.TaskAdded, Task: t})
+		writeJSON(w, http.StatusCreated, t)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleTask serves /api/tasks/{id} and its /current and /complete actions.
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/"), "/")
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		writeError(w, badRequest("invalid task id"))
+		return
+	}
+
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		t, err := s.store.GetTask(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, t)
+	case action == "" && r.Method == http.MethodPatch:
+		s.updateTask(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		t, err := s.store.GetTask(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := s.store.Remove(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		s.taskChanged(t)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "current" && r.Method == http.MethodPost:
+		if err := s.store.SetCurrentTask(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		t, err := s.store.GetTask(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.taskChanged(t)
+		writeJSON(w, http.StatusOK, t)
+	case action == "complete" && r.Method == http.MethodPost:
+		t, err := s.store.SetState(id, task.Complete)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		current, err := s.store.GetCurrentTask()
+		if err == nil && current.ID == id {
+			if err := s.store.ClearCurrentTask(id); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		s.taskChanged(t)
+		s.bus.Publish(events.Event{Type: events.TaskCompleted, Task: t})
+		writeJSON(w, http.StatusOK, t)
+	case action == "" || action == "current" || action == "complete":
+		methodNotAllowed(w)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req updateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, badRequest(err.Error()))
+		return
+	}
+
+	t, err := s.store.GetTask(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if req.Title != nil {
+		t.Title = *req.Title
+	}
+	completed := false
+	if req.Status != nil {
+		if *req.Status != task.Pending && *req.Status != task.Complete {
+			writeError(w, badRequest("status must be pending or complete"))
+			return
+		}
+		completed = t.Status != task.Complete && *req.Status == task.Complete
+		t.Status = *req.Status
+	}
+	if req.Priority != nil {
+		t.Priority = *req.Priority
+	}
+	if req.Projects != nil {
+		t.Projects = req.Projects
+	}
+	if req.Contexts != nil {
+		t.Contexts = req.Contexts
+	}
+	if req.Tags != nil {
+		t.Tags = req.Tags
+	}
+
+	now := time.Now()
+	t.UpdatedAT = &now
+	switch {
+	case completed:
+		t.CompletedAT = &now
+	case t.Status == task.Pending:
+		t.CompletedAT = nil
+	}
+	if err := s.store.Put(*t); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.taskChanged(t)
+	if completed {
+		s.bus.Publish(events.Event{Type: events.TaskCompleted, Task: t})
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) handleCurrent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	t, err := s.store.GetCurrentTask()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/aelnahas/pomo/task"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, task.Store) {
+	t.Helper()
+	db, err := database.Open("tasks", t.TempDir(), nil, task.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store := task.NewStoreOn(db)
+	web := httptest.NewServer(NewServer(store, nil, 0, nil).Handler())
+	t.Cleanup(web.Close)
+	return web, store
+}
+
+func patch(t *testing.T, url string, body interface{}) *task.Task {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH %s answered %s", url, res.Status)
+	}
+
+	var updated task.Task
+	if err := json.NewDecoder(res.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	return &updated
+}
+
+func TestUpdateTaskReopensTask(t *testing.T) {
+	web, store := newTestServer(t)
+	added, err := store.Add("write tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := web.URL + "/api/tasks/" + added.ID.String()
+
+	completed := patch(t, url, map[string]string{"status": "complete"})
+	if completed.Status != task.Complete || completed.CompletedAT == nil {
+		t.Fatalf("completing left %s completed at %v", completed.Status, completed.CompletedAT)
+	}
+
+	reopened := patch(t, url, map[string]string{"status": "pending"})
+	if reopened.Status != task.Pending || reopened.CompletedAT != nil {
+		t.Errorf("reopening left %s completed at %v", reopened.Status, reopened.CompletedAT)
+	}
+
+	stored, err := store.GetTask(added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.CompletedAT != nil {
+		t.Errorf("the stored task is still completed at %v", stored.CompletedAT)
+	}
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var web embed.FS
+
+func dashboard() http.Handler {
+	root, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(root))
+}
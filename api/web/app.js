@@ -0,0 +1,223 @@
+"use strict";
+
+const state = { current: null, timer: null };
+
+async function request(method, path, body) {
+  const res = await fetch(path, {
+    method,
+    headers: method === "GET" ? {} : { "Content-Type": "application/json" },
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  if (res.status === 204) {
+    return null;
+  }
+  const data = await res.json();
+  if (!res.ok) {
+    throw new Error(data.error || res.statusText);
+  }
+  return data;
+}
+
+function format(ns) {
+  const total = Math.max(0, Math.round(ns / 1e9));
+  const h = Math.floor(total / 3600);
+  const m = Math.floor((total % 3600) / 60);
+  const s = total % 60;
+  const pad = (n) => String(n).padStart(2, "0");
+  return h > 0 ? `${pad(h)}:${pad(m)}:${pad(s)}` : `${pad(m)}:${pad(s)}`;
+}
+
+function renderTimer(timer, label) {
+  const el = document.getElementById("timer");
+  el.className = "card";
+  if (!timer) {
+    document.getElementById("session-type").textContent = "no timer running";
+    document.getElementById("clock").textContent = "--:--";
+    document.getElementById("timer-task").textContent = "";
+    document.getElementById("timer-state").textContent = label || "";
+    return;
+  }
+
+  // a flow session counts up and has no duration
+  const shown = timer.duration ? timer.remaining || 0 : timer.elapsed || 0;
+  const remaining = timer.remaining || 0;
+  el.classList.add(timer.type);
+  if (timer.type === "focus" && timer.duration) {
+    const left = (100 * remaining) / timer.duration;
+    if (left < 25) {
+      el.classList.add("late");
+    } else if (left < 50) {
+      el.classList.add("warn");
+    }
+  }
+
+  document.getElementById("session-type").textContent = timer.type;
+  document.getElementById("clock").textContent = format(shown);
+  document.getElementById("timer-task").textContent = timer.task ? timer.task.title : "";
+  document.getElementById("timer-state").textContent = label || (timer.paused ? "paused" : "");
+}
+
+async function loadTasks() {
+  const all = document.getElementById("show-all").checked;
+  const [tasks, current] = await Promise.all([
+    request("GET", `/api/tasks?all=${all}`),
+    request("GET", "/api/current").catch(() => null),
+  ]);
+  state.current = current ? current.id : null;
+
+  const body = document.querySelector("#tasks tbody");
+  body.innerHTML = "";
+  tasks.sort((a, b) => a.created_at.localeCompare(b.created_at));
+  for (const t of tasks) {
+    const row = document.createElement("tr");
+    row.className = t.status;
+    if (t.id === state.current) {
+      row.classList.add("current");
+    }
+
+    for (const value of [t.title, t.status, t.sessions]) {
+      const cell = document.createElement("td");
+      cell.textContent = value;
+      row.appendChild(cell);
+    }
+
+    const actions = document.createElement("td");
+    if (t.status !== "complete") {
+      actions.appendChild(button("current", () => request("POST", `/api/tasks/${t.id}/current`)));
+      actions.appendChild(button("complete", () => request("POST", `/api/tasks/${t.id}/complete`)));
+    }
+    row.appendChild(actions);
+    body.appendChild(row);
+  }
+}
+
+function button(label, action) {
+  const b = document.createElement("button");
+  b.textContent = label;
+  b.addEventListener("click", async () => {
+    try {
+      await action();
+      await loadTasks();
+    } catch (err) {
+      alert(err.message);
+    }
+  });
+  return b;
+}
+
+async function loadSession() {
+  const session = await request("GET", "/api/session");
+  document.getElementById("cycle").textContent =
+    `${session.current} now, ${session.next} next, ${session.count} focus sessions`;
+}
+
+async function loadChart() {
+  const history = await request("GET", "/api/history?days=14");
+  const days = [];
+  const totals = new Map();
+  for (let i = 13; i >= 0; i--) {
+    const d = new Date();
+    d.setHours(0, 0, 0, 0);
+    d.setDate(d.getDate() - i);
+    const key = d.toISOString().slice(0, 10);
+    days.push({ key, label: `${d.getMonth() + 1}/${d.getDate()}` });
+    totals.set(key, 0);
+  }
+
+  for (const r of history) {
+    if (r.type !== "focus") {
+      continue;
+    }
+    const d = new Date(r.started_at);
+    d.setHours(0, 0, 0, 0);
+    const key = d.toISOString().slice(0, 10);
+    if (totals.has(key)) {
+      totals.set(key, totals.get(key) + r.duration / 6e10);
+    }
+  }
+
+  const svg = document.getElementById("chart");
+  const ns = "http://www.w3.org/2000/svg";
+  svg.innerHTML = "";
+  const max = Math.max(25, ...totals.values());
+  const width = 700 / days.length;
+  days.forEach((day, i) => {
+    const minutes = totals.get(day.key);
+    const height = (180 * minutes) / max;
+
+    const rect = document.createElementNS(ns, "rect");
+    rect.setAttribute("x", i * width + 6);
+    rect.setAttribute("y", 190 - height);
+    rect.setAttribute("width", width - 12);
+    rect.setAttribute("height", height);
+    const title = document.createElementNS(ns, "title");
+    title.textContent = `${Math.round(minutes)} min`;
+    rect.appendChild(title);
+    svg.appendChild(rect);
+
+    const label = document.createElementNS(ns, "text");
+    label.setAttribute("x", i * width + width / 2);
+    label.setAttribute("y", 210);
+    label.setAttribute("text-anchor", "middle");
+    label.textContent = day.label;
+    svg.appendChild(label);
+  });
+}
+
+function listen() {
+  const source = new EventSource("/api/events");
+  const timerEvents = ["started", "tick", "paused", "resumed", "extended"];
+  for (const type of timerEvents) {
+    source.addEventListener(type, (msg) => {
+      const e = JSON.parse(msg.data);
+      const previous = state.timer || {};
+      state.timer = {
+        type: e.session || previous.type,
+        task: e.task || previous.task,
+        duration: e.duration,
+        remaining: e.remaining,
+        elapsed: e.elapsed,
+        paused: type === "paused" || (type === "extended" && !!previous.paused),
+      };
+      renderTimer(state.timer);
+    });
+  }
+  source.addEventListener("completed", () => {
+    state.timer = null;
+    renderTimer(null, "completed");
+    loadSession();
+    loadChart();
+  });
+  for (const type of ["interrupted", "skipped"]) {
+    source.addEventListener(type, () => {
+      state.timer = null;
+      renderTimer(null, type);
+    });
+  }
+  source.addEventListener("task_changed", () => loadTasks());
+}
+
+document.getElementById("add-task").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const input = document.getElementById("new-title");
+  try {
+    await request("POST", "/api/tasks", { title: input.value });
+    input.value = "";
+    await loadTasks();
+  } catch (err) {
+    alert(err.message);
+  }
+});
+
+document.getElementById("show-all").addEventListener("change", loadTasks);
+
+request("GET", "/api/timer")
+  .then((t) => {
+    state.timer = t;
+    renderTimer(t);
+  })
+  .catch(() => renderTimer(null));
+loadTasks();
+loadSession();
+loadChart();
+listen();
@@ -0,0 +1,97 @@
+package archive
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+)
+
+var (
+	tasksCSVHeader   = []string{"id", "title", "status", "sessions", "priority", "projects", "contexts", "tags", "due", "created_at", "updated_at", "completed_at"}
+	historyCSVHeader = []string{"id", "type", "task_id", "started_at", "ended_at", "duration_seconds", "paused_seconds", "internal_interruptions", "external_interruptions", "abandoned"}
+	cycleCSVHeader   = []string{"current", "count"}
+)
+
+func (d *Document) WriteCSV(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	tasks := [][]string{tasksCSVHeader}
+	for _, t := range d.Tasks {
+		tasks = append(tasks, []string{
+			t.ID.String(),
+			t.Title,
+			string(t.Status),
+			strconv.Itoa(t.Sessions),
+			t.Priority,
+			strings.Join(t.Projects, " "),
+			strings.Join(t.Contexts, " "),
+			strings.Join(t.Tags, " "),
+			formatOptional(t.Due),
+			t.CreatedAT.Format(time.RFC3339),
+			formatOptional(t.UpdatedAT),
+			formatOptional(t.CompletedAT),
+		})
+	}
+
+	history := [][]string{historyCSVHeader}
+	for _, r := range d.History {
+		history = append(history, []string{
+			r.ID.String(),
+			string(r.Type),
+			r.TaskID.String(),
+			r.StartedAt.Format(time.RFC3339),
+			r.EndedAt.Format(time.RFC3339),
+			fmt.Sprintf("%.0f", r.Duration.Seconds()),
+			fmt.Sprintf("%.0f", r.Paused.Seconds()),
+			strconv.Itoa(r.Interrupted(sessions.Internal)),
+			strconv.Itoa(r.Interrupted(sessions.External)),
+			strconv.FormatBool(r.Abandoned),
+		})
+	}
+
+	cycle := [][]string{cycleCSVHeader, {string(d.Cycle.Current), strconv.Itoa(d.Cycle.Count)}}
+
+	files := map[string][][]string{
+		"tasks.csv":   tasks,
+		"history.csv": history,
+		"cycle.csv":   cycle,
+	}
+
+	for name, rows := range files {
+		if err := writeCSV(filepath.Join(dir, name), rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatOptional(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func writeCSV(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+const DocumentVersion = 1
+
+type Document struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Tasks      []task.Task       `json:"tasks"`
+	History    []sessions.Record `json:"history"`
+	Cycle      sessions.Session  `json:"cycle"`
+}
+
+func Build(store task.Store, sessionStore sessions.Store) (*Document, error) {
+	tasks, err := store.List(func(t task.Task) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := sessionStore.History()
+	if err != nil {
+		return nil, err
+	}
+
+	cycle, err := sessionStore.Session()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		Version:    DocumentVersion,
+		ExportedAt: time.Now(),
+		Tasks:      tasks,
+		History:    history,
+		Cycle:      *cycle,
+	}, nil
+}
+
+func ReadJSON(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Version < 1 || doc.Version > DocumentVersion {
+		return nil, fmt.Errorf("unsupported export version %d (supported up to %d)", doc.Version, DocumentVersion)
+	}
+
+	return &doc, nil
+}
+
+func (d *Document) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
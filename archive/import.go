@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+type Strategy string
+
+const (
+	Skip      Strategy = "skip"
+	Overwrite Strategy = "overwrite"
+	Merge     Strategy = "merge"
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case Skip, Overwrite, Merge:
+		return Strategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown conflict strategy %q (expected skip, overwrite or merge)", s)
+	}
+}
+
+type Summary struct {
+	Added   int
+	Updated int
+	Skipped int
+}
+
+func Import(doc *Document, store task.Store, sessionStore sessions.Store, strategy Strategy) (*Summary, error) {
+	summary := &Summary{}
+
+	for _, incoming := range doc.Tasks {
+		existing, err := store.GetTask(incoming.ID)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			if err := store.Put(incoming); err != nil {
+				return nil, err
+			}
+			summary.Added++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch strategy {
+		case Skip:
+			summary.Skipped++
+			continue
+		case Merge:
+			incoming = MergeTasks(*existing, incoming)
+		}
+
+		if err := store.Put(incoming); err != nil {
+			return nil, err
+		}
+		summary.Updated++
+	}
+
+	history, err := sessionStore.History()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[uuid.UUID]bool, len(history))
+	for _, r := range history {
+		known[r.ID] = true
+	}
+
+	for _, r := range doc.History {
+		if known[r.ID] && strategy != Overwrite {
+			summary.Skipped++
+			continue
+		}
+
+		if err := sessionStore.Record(r); err != nil {
+			return nil, err
+		}
+		if known[r.ID] {
+			summary.Updated++
+		} else {
+			summary.Added++
+		}
+	}
+
+	cycle, err := sessionStore.Session()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strategy == Overwrite,
+		strategy == Merge && doc.Cycle.Count > cycle.Count:
+		if err := sessionStore.SetSession(doc.Cycle); err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+// MergeTasks combines two copies of the same task: the most recently updated
+// copy wins for descriptive fields, completion is sticky and session counts
+// never go backwards.
+func MergeTasks(local, incoming task.Task) task.Task {
+	merged := local
+	if newer(incoming, local) {
+		merged = incoming
+	}
+
+	if local.Status == task.Complete || incoming.Status == task.Complete {
+		merged.Status = task.Complete
+	}
+
+	if incoming.Sessions > merged.Sessions {
+		merged.Sessions = incoming.Sessions
+	}
+	if local.Sessions > merged.Sessions {
+		merged.Sessions = local.Sessions
+	}
+
+	return merged
+}
+
+func newer(a, b task.Task) bool {
+	switch {
+	case a.UpdatedAT == nil:
+		return false
+	case b.UpdatedAT == nil:
+		return true
+	default:
+		return a.UpdatedAT.After(*b.UpdatedAT)
+	}
+}
@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/google/uuid"
+)
+
+func (d *Document) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	focus := make(map[uuid.UUID]time.Duration)
+	for _, r := range d.History {
+		if r.Type == sessions.Focus {
+			focus[r.TaskID] += r.Duration
+		}
+	}
+
+	fmt.Fprintf(&b, "# Pomo report\n\n")
+	fmt.Fprintf(&b, "Exported %s\n\n", d.ExportedAt.Format(time.RFC1123))
+
+	fmt.Fprintf(&b, "## Cycle\n\n")
+	fmt.Fprintf(&b, "- current: %s\n", d.Cycle.Current)
+	fmt.Fprintf(&b, "- completed focus sessions: %d\n\n", d.Cycle.Count)
+
+	fmt.Fprintf(&b, "## Tasks\n\n")
+	fmt.Fprintf(&b, "| title | status | sessions | focus time | created |\n")
+	fmt.Fprintf(&b, "| --- | --- | ---: | ---: | --- |\n")
+	for _, t := range d.Tasks {
+		fmt.Fprintf(&b, "| %s | %s | %d | %s | %s |\n",
+			escapeMarkdown(t.Title), t.Status, t.Sessions, focus[t.ID].Round(time.Second), t.CreatedAT.Format("2006-01-02"))
+	}
+
+	fmt.Fprintf(&b, "\n## History\n\n")
+	fmt.Fprintf(&b, "| started | type | task | duration |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- | ---: |\n")
+	titles := make(map[uuid.UUID]string)
+	for _, t := range d.Tasks {
+		titles[t.ID] = t.Title
+	}
+	for _, r := range d.History {
+		title, ok := titles[r.TaskID]
+		if !ok {
+			title = r.TaskID.String()
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+			r.StartedAt.Format("2006-01-02 15:04"), r.Label(), escapeMarkdown(title), r.Duration.Round(time.Second))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
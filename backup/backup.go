@@ -0,0 +1,251 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/database"
+)
+
+const (
+	ManifestVersion = 1
+	manifestName    = "manifest.json"
+	filePrefix      = "pomo-"
+	fileSuffix      = ".tar.gz"
+	timeLayout      = "20060102T150405"
+)
+
+type Manifest struct {
+	Version     int        `json:"version"`
+	PomoVersion string     `json:"pomo_version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Databases   []Database `json:"databases"`
+}
+
+type Database struct {
+	Name          string `json:"name"`
+	File          string `json:"file"`
+	SchemaVersion int    `json:"schema_version"`
+	Encrypted     bool   `json:"encrypted"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+}
+
+// Create streams every database into a gzipped tarball under dir, followed by
+// a manifest describing each entry so restore can verify the archive.
+func Create(dir, pomoVersion string, dbs ...*database.DB) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	path, f, err := create(dir, now)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	manifest := Manifest{
+		Version:     ManifestVersion,
+		PomoVersion: pomoVersion,
+		CreatedAt:   now,
+	}
+
+	for _, db := range dbs {
+		entry, err := writeDatabase(tw, db, now)
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("%s: %w", db.Name, err)
+		}
+		manifest.Databases = append(manifest.Databases, *entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeEntry(tw, manifestName, data, now); err != nil {
+		return "", err
+	}
+
+	for _, closer := range []io.Closer{tw, gz} {
+		if err := closer.Close(); err != nil {
+			return "", err
+		}
+	}
+
+	return path, f.Sync()
+}
+
+// create never replaces an existing archive, snapshots taken within the same
+// second get a numeric suffix.
+func create(dir string, now time.Time) (string, *os.File, error) {
+	base := filePrefix + now.Format(timeLayout)
+	for i := 0; ; i++ {
+		name := base + fileSuffix
+		if i > 0 {
+			name = fmt.Sprintf("%s.%d%s", base, i, fileSuffix)
+		}
+
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return path, f, err
+	}
+}
+
+func writeDatabase(tw *tar.Writer, db *database.DB, modTime time.Time) (*Database, error) {
+	schema, err := db.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := db.Backup(&buf, 0); err != nil {
+		return nil, err
+	}
+
+	data, err := db.Encrypt(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	entry := &Database{
+		Name:          db.Name,
+		File:          db.Name + ".bak",
+		SchemaVersion: schema,
+		Encrypted:     db.Encrypted(),
+		Size:          int64(len(data)),
+		SHA256:        hex.EncodeToString(sum[:]),
+	}
+
+	if err := writeEntry(tw, entry.File, data, modTime); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(data)
+	return err
+}
+
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			backups = append(backups, filepath.Join(dir, name))
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		si, ni := sequence(backups[i])
+		sj, nj := sequence(backups[j])
+		if si != sj {
+			return si < sj
+		}
+		return ni < nj
+	})
+	return backups, nil
+}
+
+// sequence splits an archive name into its timestamp, which sorts lexically,
+// and the counter create adds for snapshots taken within the same second.
+func sequence(path string) (string, int) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), filePrefix), fileSuffix)
+	stamp, counter := name, 0
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		stamp = name[:i]
+		counter, _ = strconv.Atoi(name[i+1:])
+	}
+	return stamp, counter
+}
+
+func Rotate(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := List(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// Daily creates a backup when the newest one in dir is older than a day and
+// prunes the directory down to keep archives.
+func Daily(dir, pomoVersion string, keep int, dbs ...*database.DB) (string, error) {
+	backups, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// the newest archive with a readable name, counter suffix and all, decides
+	for i := len(backups) - 1; i >= 0; i-- {
+		stamp, _ := sequence(backups[i])
+		last, err := time.ParseInLocation(timeLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		if time.Since(last) < 24*time.Hour {
+			return "", nil
+		}
+		break
+	}
+
+	path, err := Create(dir, pomoVersion, dbs...)
+	if err != nil {
+		return "", err
+	}
+
+	return path, Rotate(dir, keep)
+}
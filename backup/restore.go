@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aelnahas/pomo/database"
+)
+
+var ErrCorrupt = errors.New("backup archive failed verification")
+
+// Verify reads the whole archive, checking every database entry against the
+// manifest. The extracted entries are kept in temporary files keyed by
+// database name; callers must call cleanup once they are done with them.
+func Verify(path string) (manifest *Manifest, files map[string]string, cleanup func(), err error) {
+	extracted := make(map[string]string)
+	cleanup = func() {
+		for _, f := range extracted {
+			os.Remove(f)
+		}
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrCorrupt, err)
+	}
+	defer gz.Close()
+
+	sums := make(map[string]string)
+	sizes := make(map[string]int64)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %s", ErrCorrupt, err)
+		}
+
+		if header.Name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, nil, fmt.Errorf("%w: invalid manifest (%s)", ErrCorrupt, err)
+			}
+			continue
+		}
+
+		tmp, err := os.CreateTemp("", "pomo-restore-*")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		extracted[header.Name] = tmp.Name()
+
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(tmp, hash), tr)
+		tmp.Close()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %s", ErrCorrupt, err)
+		}
+
+		sums[header.Name] = hex.EncodeToString(hash.Sum(nil))
+		sizes[header.Name] = size
+	}
+
+	if manifest == nil {
+		return nil, nil, nil, fmt.Errorf("%w: missing manifest", ErrCorrupt)
+	}
+	if manifest.Version > ManifestVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported backup manifest version %d", manifest.Version)
+	}
+
+	files = make(map[string]string)
+	for _, db := range manifest.Databases {
+		if sums[db.File] != db.SHA256 || sizes[db.File] != db.Size {
+			return nil, nil, nil, fmt.Errorf("%w: checksum mismatch for %s", ErrCorrupt, db.File)
+		}
+		files[db.Name] = extracted[db.File]
+	}
+
+	return manifest, files, cleanup, nil
+}
+
+// Restore verifies the archive and replaces the contents of each database
+// with the copy found in it.
+func Restore(path string, dbs ...*database.DB) (*Manifest, error) {
+	manifest, files, cleanup, err := Verify(path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	entries := make(map[string]Database)
+	for _, entry := range manifest.Databases {
+		entries[entry.Name] = entry
+	}
+
+	for _, db := range dbs {
+		entry, ok := entries[db.Name]
+		if !ok {
+			return nil, fmt.Errorf("backup does not contain the %s database", db.Name)
+		}
+		if entry.SchemaVersion > db.Latest() {
+			return nil, fmt.Errorf("%s: %w (backup v%d, latest v%d)", db.Name, database.ErrNewerSchema, entry.SchemaVersion, db.Latest())
+		}
+	}
+
+	// decrypt everything up front so a wrong key never leaves one database
+	// restored and the other dropped
+	contents := make(map[string][]byte)
+	for _, db := range dbs {
+		data, err := os.ReadFile(files[db.Name])
+		if err != nil {
+			return nil, err
+		}
+
+		if entries[db.Name].Encrypted {
+			if data, err = db.Decrypt(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", db.Name, err)
+			}
+		}
+		contents[db.Name] = data
+	}
+
+	for _, db := range dbs {
+		if err := db.DropAll(); err != nil {
+			return nil, fmt.Errorf("%s: %w", db.Name, err)
+		}
+
+		if err := db.Load(bytes.NewReader(contents[db.Name]), 256); err != nil {
+			return nil, fmt.Errorf("%s: %w", db.Name, err)
+		}
+	}
+
+	return manifest, nil
+}
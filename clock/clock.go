@@ -0,0 +1,52 @@
+// Package clock lets timing code run against the real clock or a fake one
+// that only moves when told to.
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer mirrors time.Timer behind an interface so fakes can provide it.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Real is the system clock. Times it returns carry monotonic readings.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// Drain stops t and empties its channel, so a following Reset starts
+// clean whether or not t had already fired.
+func Drain(t Timer) {
+	if t != nil && !t.Stop() {
+		select {
+		case <-t.C():
+		default:
+		}
+	}
+}
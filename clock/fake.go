@@ -0,0 +1,124 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a clock that stands still until Advance is called, for driving
+// timing code step by step.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), deadline: f.now.Add(d), active: true}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing due timers in deadline order
+// with the clock set to each deadline as it fires.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := f.now.Add(d)
+	for {
+		due := f.due(target)
+		if due == nil {
+			break
+		}
+
+		f.now = due.deadline
+		due.active = false
+		select {
+		case due.c <- f.now:
+		default:
+		}
+	}
+	f.now = target
+}
+
+// Pending returns the number of timers waiting to fire.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, t := range f.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+// due returns the earliest active timer at or before target. The caller
+// must hold mu.
+func (f *Fake) due(target time.Time) *fakeTimer {
+	active := make([]*fakeTimer, 0, len(f.timers))
+	for _, t := range f.timers {
+		if t.active && !t.deadline.After(target) {
+			active = append(active, t)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].deadline.Before(active[j].deadline)
+	})
+	return active[0]
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	was := t.active
+	t.active = false
+	return was
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	was := t.active
+	t.deadline = t.clock.now.Add(d)
+	t.active = true
+	return was
+}
@@ -1,12 +1,13 @@
 package add
 
 import (
+	"github.com/aelnahas/pomo/events"
 	"github.com/aelnahas/pomo/output"
 	"github.com/aelnahas/pomo/task"
 	"github.com/spf13/cobra"
 )
 
-func NewCmd(version string, store task.Store) *cobra.Command {
+func NewCmd(version string, store task.Store, bus *events.Bus) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "add <title>",
 		Aliases: []string{"a"},
@@ -18,6 +19,7 @@ func NewCmd(version string, store task.Store) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			bus.Publish(events.Event{Type: events.TaskAdded, Task: newTask})
 			output.Printlist(*newTask)
 			return nil
 		},
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"fmt"
+
+	"github.com/aelnahas/pomo/backup"
+	"github.com/aelnahas/pomo/database"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	dir string
+}
+
+func NewCmd(version, pomoVersion, backupDir string, dbs ...*database.DB) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "backup [flags]",
+		Short:   "snapshot the task and session databases",
+		Long:    "write a compressed snapshot of the task and session databases along with a manifest used to verify it on restore",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := backup.Create(opts.directory(backupDir), pomoVersion, dbs...)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(path)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newListCmd(version, backupDir, &opts))
+	cmd.PersistentFlags().StringVarP(&opts.dir, "dir", "d", "", "snapshot directory, defaults to database.backups")
+	return cmd
+}
+
+// directory is --dir, or backupDir when it is not given.
+func (o *options) directory(backupDir string) string {
+	if o.dir == "" {
+		return backupDir
+	}
+	return o.dir
+}
+
+func newListCmd(version, backupDir string, opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "list snapshots, oldest first",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backups, err := backup.List(opts.directory(backupDir))
+			if err != nil {
+				return err
+			}
+
+			for _, path := range backups {
+				fmt.Println(path)
+			}
+			return nil
+		},
+	}
+}
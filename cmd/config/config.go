@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -9,24 +10,53 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/aelnahas/pomo/hooks"
+	"github.com/aelnahas/pomo/keys"
+	"github.com/aelnahas/pomo/notify"
+	"github.com/aelnahas/pomo/sound"
+	"github.com/aelnahas/pomo/webhooks"
 )
 
 const (
 	PomoDir    = "~/.pomo"
 	PomoConfig = "config.toml"
 	Template   = "config.template.toml"
+	BackupDir  = "~/.pomo/backups"
+	SaltFile   = "~/.pomo/encryption.salt"
+	HooksLog   = "~/.pomo/hooks.log"
+	OutboxDir  = "~/.pomo/outbox"
 )
 
 var DefaultPath = fmt.Sprintf("%s/%s", PomoDir, PomoConfig)
 
 type Config struct {
-	Database Database    `toml:"database"`
-	Timers   TimerConfig `toml:"timers"`
+	Database      Database            `toml:"database"`
+	Timers        TimerConfig         `toml:"timers"`
+	TodoTxt       TodoTxtConfig       `toml:"todotxt"`
+	Backup        BackupConfig        `toml:"backup"`
+	Encryption    EncryptionConfig    `toml:"encryption"`
+	Sync          SyncConfig          `toml:"sync"`
+	API           APIConfig           `toml:"api"`
+	Hooks         HooksConfig         `toml:"hooks"`
+	Webhooks      WebhooksConfig      `toml:"webhooks"`
+	Notifications NotificationsConfig `toml:"notifications"`
+	Sound         SoundConfig         `toml:"sound"`
+	Flow          FlowConfig          `toml:"flow"`
+	Partial       PartialConfig       `toml:"partial"`
+	Auto          AutoConfig          `toml:"auto"`
 }
 
 type Database struct {
 	Task    string `toml:"task"`
 	Session string `toml:"session"`
+	Backups string `toml:"backups"`
+}
+
+func (d *Database) BackupPath() (string, error) {
+	if d.Backups == "" {
+		return ExpandPath(BackupDir)
+	}
+	return ExpandPath(d.Backups)
 }
 
 func ExpandPath(path string) (string, error) {
@@ -40,11 +70,299 @@ func ExpandPath(path string) (string, error) {
 	return path, nil
 }
 
+type HooksConfig struct {
+	Timeout        string `toml:"timeout"`
+	Log            string `toml:"log"`
+	OnStart        string `toml:"on_start"`
+	OnPause        string `toml:"on_pause"`
+	OnResume       string `toml:"on_resume"`
+	OnComplete     string `toml:"on_complete"`
+	OnInterrupt    string `toml:"on_interrupt"`
+	OnBreakStart   string `toml:"on_break_start"`
+	OnTaskComplete string `toml:"on_task_complete"`
+}
+
+func (h *HooksConfig) Runner() (*hooks.Runner, error) {
+	var timeout time.Duration
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hooks.timeout (%w)", err)
+		}
+		timeout = d
+	}
+
+	logPath := h.Log
+	if logPath == "" {
+		logPath = HooksLog
+	}
+	logPath, err := ExpandPath(logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return hooks.New(map[hooks.Name]string{
+		hooks.OnStart:        h.OnStart,
+		hooks.OnPause:        h.OnPause,
+		hooks.OnResume:       h.OnResume,
+		hooks.OnComplete:     h.OnComplete,
+		hooks.OnInterrupt:    h.OnInterrupt,
+		hooks.OnBreakStart:   h.OnBreakStart,
+		hooks.OnTaskComplete: h.OnTaskComplete,
+	}, timeout, logPath), nil
+}
+
+type SoundConfig struct {
+	Enabled  bool   `toml:"enabled"`
+	Player   string `toml:"player"`
+	Ticking  bool   `toml:"ticking"`
+	FocusEnd string `toml:"focus_end"`
+	ShortEnd string `toml:"short_end"`
+	LongEnd  string `toml:"long_end"`
+	Tick     string `toml:"tick"`
+}
+
+// NewPlayer returns the configured player, or one that stays silent when
+// sound is disabled. Empty file settings use the bundled sounds.
+func (s *SoundConfig) NewPlayer() (sound.Player, error) {
+	if !s.Enabled {
+		return sound.Nop{}, nil
+	}
+
+	files := make(map[sound.Sound]string)
+	for name, path := range map[sound.Sound]string{
+		sound.FocusEnd: s.FocusEnd,
+		sound.ShortEnd: s.ShortEnd,
+		sound.LongEnd:  s.LongEnd,
+		sound.Tick:     s.Tick,
+	} {
+		if path == "" {
+			continue
+		}
+		expanded, err := ExpandPath(path)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = expanded
+	}
+
+	return sound.New(s.Player, files, os.Stdout)
+}
+
+type NotificationsConfig struct {
+	Backend string `toml:"backend"`
+	Wait    string `toml:"wait"`
+	Snooze  string `toml:"snooze"`
+}
+
+func (n *NotificationsConfig) Notifier() (notify.Notifier, error) {
+	return notify.New(n.Backend, os.Stdout)
+}
+
+// Durations returns how long to wait for a notification action and how
+// long a snooze lasts.
+func (n *NotificationsConfig) Durations() (wait, snooze time.Duration, err error) {
+	wait, snooze = time.Minute, 5*time.Minute
+	if n.Wait != "" {
+		if wait, err = time.ParseDuration(n.Wait); err != nil {
+			return 0, 0, fmt.Errorf("invalid notifications.wait (%w)", err)
+		}
+	}
+	if n.Snooze != "" {
+		if snooze, err = time.ParseDuration(n.Snooze); err != nil {
+			return 0, 0, fmt.Errorf("invalid notifications.snooze (%w)", err)
+		}
+	}
+	return wait, snooze, nil
+}
+
+const (
+	FlowProportional = "proportional"
+	FlowWhole        = "whole"
+)
+
+type FlowConfig struct {
+	Credit     string  `toml:"credit"`
+	BreakRatio float64 `toml:"break_ratio"`
+}
+
+// Pomodoros is what a flow session that ran for elapsed is worth, in focus
+// sessions of the given length.
+func (f *FlowConfig) Pomodoros(elapsed, focus time.Duration) (float64, error) {
+	if focus <= 0 {
+		return 0, nil
+	}
+
+	pomodoros := float64(elapsed) / float64(focus)
+	switch f.Credit {
+	case "", FlowProportional:
+		return pomodoros, nil
+	case FlowWhole:
+		return math.Floor(pomodoros), nil
+	}
+	return 0, fmt.Errorf("invalid flow.credit %q", f.Credit)
+}
+
+// Break is the break earned by a flow session that ran for elapsed, a
+// fifth of it unless break_ratio says otherwise.
+func (f *FlowConfig) Break(elapsed time.Duration) time.Duration {
+	ratio := f.BreakRatio
+	if ratio <= 0 {
+		ratio = 0.2
+	}
+	return time.Duration(float64(elapsed) * ratio).Round(time.Second)
+}
+
+const (
+	PartialNone         = "none"
+	PartialProportional = "proportional"
+	PartialWhole        = "whole"
+)
+
+type PartialConfig struct {
+	Credit    string  `toml:"credit"`
+	Threshold float64 `toml:"threshold"`
+}
+
+// Pomodoros is what an abandoned focus session that ran for elapsed is
+// worth, in focus sessions of the given length.
+func (p *PartialConfig) Pomodoros(elapsed, focus time.Duration) (float64, error) {
+	if focus <= 0 {
+		return 0, nil
+	}
+
+	ran := float64(elapsed) / float64(focus)
+	switch p.Credit {
+	case "", PartialNone:
+		return 0, nil
+	case PartialProportional:
+		return math.Min(ran, 1), nil
+	case PartialWhole:
+		if ran >= p.Threshold {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("invalid partial.credit %q", p.Credit)
+}
+
+// AutoConfig are the defaults of timer start --auto. Cycles is how many
+// focus sessions, each with its break, to run before stopping, 0 for no
+// limit.
+type AutoConfig struct {
+	Enabled bool `toml:"enabled"`
+	Confirm bool `toml:"confirm"`
+	Cycles  int  `toml:"cycles"`
+}
+
+type WebhooksConfig struct {
+	Outbox      string          `toml:"outbox"`
+	Timeout     string          `toml:"timeout"`
+	MaxAttempts int             `toml:"max_attempts"`
+	Targets     []WebhookTarget `toml:"targets"`
+}
+
+type WebhookTarget struct {
+	URL    string   `toml:"url"`
+	Secret string   `toml:"secret"`
+	Events []string `toml:"events"`
+}
+
+func (w *WebhooksConfig) Sender() (*webhooks.Sender, error) {
+	var timeout time.Duration
+	if w.Timeout != "" {
+		d, err := time.ParseDuration(w.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhooks.timeout (%w)", err)
+		}
+		timeout = d
+	}
+
+	dir := w.Outbox
+	if dir == "" {
+		dir = OutboxDir
+	}
+	dir, err := ExpandPath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	outbox := webhooks.NewOutbox(dir)
+
+	targets := make([]webhooks.Target, 0, len(w.Targets))
+	for _, t := range w.Targets {
+		if t.URL == "" {
+			return nil, fmt.Errorf("webhook target is missing a url")
+		}
+		targets = append(targets, webhooks.Target{URL: t.URL, Secret: t.Secret, Events: t.Events})
+	}
+
+	return webhooks.NewSender(targets, outbox, timeout, w.MaxAttempts), nil
+}
+
+type APIConfig struct {
+	Listen string `toml:"listen"`
+}
+
+type SyncConfig struct {
+	Dir    string `toml:"dir"`
+	Device string `toml:"device"`
+}
+
+func (s *SyncConfig) DeviceName() (string, error) {
+	if s.Device != "" {
+		return s.Device, nil
+	}
+	return os.Hostname()
+}
+
+type EncryptionConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Keyfile string `toml:"keyfile"`
+	Salt    string `toml:"salt"`
+}
+
+func (e *EncryptionConfig) SaltPath() (string, error) {
+	if e.Salt == "" {
+		return ExpandPath(SaltFile)
+	}
+	return ExpandPath(e.Salt)
+}
+
+func (e *EncryptionConfig) Key() ([]byte, error) {
+	if !e.Enabled {
+		return nil, nil
+	}
+
+	keyfile, err := ExpandPath(e.Keyfile)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := e.SaltPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.Load(keyfile, salt)
+}
+
+type BackupConfig struct {
+	Auto bool `toml:"auto"`
+	Keep int  `toml:"keep"`
+}
+
+type TodoTxtConfig struct {
+	Path string `toml:"path"`
+}
+
 type TimerConfig struct {
 	Focus    string `toml:"focus"`
 	Short    string `toml:"short"`
 	Long     string `toml:"long"`
 	Interval int    `toml:"interval"`
+	MaxPause string `toml:"max_pause"`
+	Extend   string `toml:"extend"`
 }
 
 func (tc *TimerConfig) FocusDuration() time.Duration {
@@ -71,6 +389,34 @@ func (tc *TimerConfig) LongBreakDuration() time.Duration {
 	return d
 }
 
+// MaxPauseDuration is how long a timer may stay paused before the session
+// is abandoned, zero when there is no limit.
+func (tc *TimerConfig) MaxPauseDuration() time.Duration {
+	if tc.MaxPause == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(tc.MaxPause)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// ExtendDuration is how much + adds to a running session, five minutes
+// unless set.
+func (tc *TimerConfig) ExtendDuration() time.Duration {
+	if tc.Extend == "" {
+		return 5 * time.Minute
+	}
+
+	d, err := time.ParseDuration(tc.Extend)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 type duration struct {
 	time.Duration
 }
@@ -102,10 +448,12 @@ func Parse(path string) (*Config, error) {
 func Update(key, value string, config *Config) error {
 	if strings.HasPrefix(key, "database.") {
 		switch {
-		case strings.HasPrefix(key, "task"):
+		case strings.HasSuffix(key, "task"):
 			config.Database.Task = value
-		case strings.HasPrefix(key, "session"):
+		case strings.HasSuffix(key, "session"):
 			config.Database.Session = value
+		case strings.HasSuffix(key, "backups"):
+			config.Database.Backups = value
 		default:
 			return fmt.Errorf("unknown database %s", key)
 		}
@@ -136,6 +484,224 @@ func Update(key, value string, config *Config) error {
 				return err
 			}
 			config.Timers.Interval = intervals
+		case strings.HasSuffix(key, "max_pause"):
+			if value == "" {
+				config.Timers.MaxPause = ""
+				break
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			config.Timers.MaxPause = d.String()
+		case strings.HasSuffix(key, "extend"):
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			config.Timers.Extend = d.String()
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "backup.") {
+		switch {
+		case strings.HasSuffix(key, "auto"):
+			auto, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			config.Backup.Auto = auto
+		case strings.HasSuffix(key, "keep"):
+			keep, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			config.Backup.Keep = keep
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "encryption.") {
+		switch {
+		case strings.HasSuffix(key, "enabled"):
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			config.Encryption.Enabled = enabled
+		case strings.HasSuffix(key, "keyfile"):
+			config.Encryption.Keyfile = value
+		case strings.HasSuffix(key, "salt"):
+			config.Encryption.Salt = value
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "api.") {
+		switch {
+		case strings.HasSuffix(key, "listen"):
+			config.API.Listen = value
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "sync.") {
+		switch {
+		case strings.HasSuffix(key, "dir"):
+			config.Sync.Dir = value
+		case strings.HasSuffix(key, "device"):
+			config.Sync.Device = value
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "hooks.") {
+		switch key {
+		case "hooks.timeout":
+			if _, err := time.ParseDuration(value); err != nil {
+				return err
+			}
+			config.Hooks.Timeout = value
+		case "hooks.log":
+			config.Hooks.Log = value
+		case "hooks.on_start":
+			config.Hooks.OnStart = value
+		case "hooks.on_pause":
+			config.Hooks.OnPause = value
+		case "hooks.on_resume":
+			config.Hooks.OnResume = value
+		case "hooks.on_complete":
+			config.Hooks.OnComplete = value
+		case "hooks.on_interrupt":
+			config.Hooks.OnInterrupt = value
+		case "hooks.on_break_start":
+			config.Hooks.OnBreakStart = value
+		case "hooks.on_task_complete":
+			config.Hooks.OnTaskComplete = value
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "notifications.") {
+		switch key {
+		case "notifications.backend":
+			switch value {
+			case notify.Auto, notify.DBus, notify.NotifySend, notify.Bell, notify.None:
+			default:
+				return fmt.Errorf("unknown notification backend %q", value)
+			}
+			config.Notifications.Backend = value
+		case "notifications.wait":
+			if _, err := time.ParseDuration(value); err != nil {
+				return err
+			}
+			config.Notifications.Wait = value
+		case "notifications.snooze":
+			if _, err := time.ParseDuration(value); err != nil {
+				return err
+			}
+			config.Notifications.Snooze = value
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "sound.") {
+		switch key {
+		case "sound.enabled", "sound.ticking":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			if key == "sound.enabled" {
+				config.Sound.Enabled = enabled
+			} else {
+				config.Sound.Ticking = enabled
+			}
+		case "sound.player":
+			config.Sound.Player = value
+		case "sound.focus_end":
+			config.Sound.FocusEnd = value
+		case "sound.short_end":
+			config.Sound.ShortEnd = value
+		case "sound.long_end":
+			config.Sound.LongEnd = value
+		case "sound.tick":
+			config.Sound.Tick = value
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "flow.") {
+		switch key {
+		case "flow.credit":
+			if value != FlowProportional && value != FlowWhole {
+				return fmt.Errorf("flow.credit is %s or %s", FlowProportional, FlowWhole)
+			}
+			config.Flow.Credit = value
+		case "flow.break_ratio":
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			config.Flow.BreakRatio = ratio
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "partial.") {
+		switch key {
+		case "partial.credit":
+			if value != PartialNone && value != PartialProportional && value != PartialWhole {
+				return fmt.Errorf("partial.credit is %s, %s or %s", PartialNone, PartialProportional, PartialWhole)
+			}
+			config.Partial.Credit = value
+		case "partial.threshold":
+			threshold, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			config.Partial.Threshold = threshold
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "auto.") {
+		switch key {
+		case "auto.enabled", "auto.confirm":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			if key == "auto.enabled" {
+				config.Auto.Enabled = enabled
+			} else {
+				config.Auto.Confirm = enabled
+			}
+		case "auto.cycles":
+			cycles, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			if cycles < 0 {
+				return fmt.Errorf("auto.cycles can not be negative")
+			}
+			config.Auto.Cycles = cycles
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+	} else if strings.HasPrefix(key, "webhooks.") {
+		switch key {
+		case "webhooks.outbox":
+			config.Webhooks.Outbox = value
+		case "webhooks.timeout":
+			if _, err := time.ParseDuration(value); err != nil {
+				return err
+			}
+			config.Webhooks.Timeout = value
+		case "webhooks.max_attempts":
+			attempts, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			config.Webhooks.MaxAttempts = attempts
+		default:
+			return fmt.Errorf("unknown key %s, targets are edited in the config file", key)
+		}
+	} else if strings.HasPrefix(key, "todotxt.") {
+		switch {
+		case strings.HasSuffix(key, "path"):
+			config.TodoTxt.Path = value
 		default:
 			return fmt.Errorf("unknown key %s", key)
 		}
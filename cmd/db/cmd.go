@@ -0,0 +1,303 @@
+package db
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+
+	"github.com/aelnahas/pomo/backup"
+	"github.com/aelnahas/pomo/build"
+	"github.com/aelnahas/pomo/cmd/config"
+	"github.com/aelnahas/pomo/database"
+	"github.com/aelnahas/pomo/keys"
+	"github.com/aelnahas/pomo/maintenance"
+	"github.com/aelnahas/pomo/output"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	dryRun   bool
+	compact  bool
+	keyfile  string
+	generate string
+	decrypt  bool
+}
+
+func NewCmd(version string, c *config.Config, backupDir string, tasks, sessions *database.DB) *cobra.Command {
+	dbs := []*database.DB{tasks, sessions}
+	cmd := &cobra.Command{
+		Use:     "db <command> [flags]",
+		Short:   "manage pomo databases",
+		Version: version,
+		// db commands operate on the databases as found on disk, so skip the
+		// automatic migrations the root command runs before every command.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newMigrateCmd(version, backupDir, dbs...))
+	cmd.AddCommand(newGCCmd(version, dbs...))
+	cmd.AddCommand(newCheckCmd(version, tasks, sessions))
+	cmd.AddCommand(newRepairCmd(version, backupDir, tasks, sessions))
+	cmd.AddCommand(newRekeyCmd(version, c, dbs...))
+	return cmd
+}
+
+func newMigrateCmd(version string, backupDir string, dbs ...*database.DB) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "migrate [flags]",
+		Short:   "upgrade databases to the latest schema",
+		Long:    "upgrade databases to the latest schema, backing them up first",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, db := range dbs {
+				current, err := db.Version()
+				if err != nil {
+					return err
+				}
+
+				if opts.dryRun {
+					pending, err := db.Pending()
+					if err != nil {
+						return err
+					}
+					if len(pending) == 0 {
+						fmt.Printf("%s: up to date (v%d)\n", db.Name, current)
+						continue
+					}
+					output.PrintMigrations(db.Name, current, pending...)
+					continue
+				}
+
+				applied, err := db.Migrate(backupDir)
+				if err != nil {
+					return err
+				}
+				if len(applied) == 0 {
+					fmt.Printf("%s: up to date (v%d)\n", db.Name, current)
+					continue
+				}
+				fmt.Printf("%s: migrated v%d -> v%d\n", db.Name, current, applied[len(applied)-1].Version)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newStatusCmd(version, dbs...))
+	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "list pending migrations without applying them")
+	return cmd
+}
+
+func newStatusCmd(version string, dbs ...*database.DB) *cobra.Command {
+	return &cobra.Command{
+		Use:     "status",
+		Short:   "show the schema version of each database",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, db := range dbs {
+				current, err := db.Version()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("%s: v%d (latest v%d)\n", db.Name, current, db.Latest())
+				output.PrintMigrations(db.Name, current, db.Migrations()...)
+			}
+			return nil
+		},
+	}
+}
+
+func newGCCmd(version string, dbs ...*database.DB) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "gc [flags]",
+		Short:   "reclaim space from the value logs",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, db := range dbs {
+				if opts.compact {
+					if err := db.Compact(); err != nil {
+						return fmt.Errorf("%s: %w", db.Name, err)
+					}
+				}
+
+				rewritten, err := db.CollectGarbage(0.5)
+				if err != nil {
+					return fmt.Errorf("%s: %w", db.Name, err)
+				}
+				fmt.Printf("%s: rewrote %d value log files\n", db.Name, rewritten)
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().BoolVar(&opts.compact, "compact", false, "flatten the LSM tree before collecting garbage")
+	return cmd
+}
+
+func newCheckCmd(version string, tasks, sessions *database.DB) *cobra.Command {
+	return &cobra.Command{
+		Use:     "check",
+		Short:   "validate every record and cross reference",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems, err := maintenance.Check(tasks, sessions)
+			if err != nil {
+				return err
+			}
+
+			if len(problems) == 0 {
+				fmt.Println("no problems found")
+				return nil
+			}
+
+			output.PrintProblems(problems...)
+			repairable := len(maintenance.Repairable(problems))
+			if repairable == 0 {
+				fmt.Println("history of removed tasks is kept, there is nothing to repair")
+				return nil
+			}
+			return fmt.Errorf("found %d problems, run `pomo db repair` to fix %d of them", len(problems), repairable)
+		},
+	}
+}
+
+func newRepairCmd(version string, backupDir string, tasks, sessions *database.DB) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "repair [flags]",
+		Short:   "remove records reported by check",
+		Long:    "remove records reported by check, taking a backup first. History of removed tasks is only reported, it is kept for stats and exports",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems, err := maintenance.Check(tasks, sessions)
+			if err != nil {
+				return err
+			}
+
+			problems = maintenance.Repairable(problems)
+			if len(problems) == 0 {
+				fmt.Println("no problems to repair")
+				return nil
+			}
+
+			output.PrintProblems(problems...)
+			if opts.dryRun {
+				return nil
+			}
+
+			path, err := backup.Create(backupDir, build.Version, tasks, sessions)
+			if err != nil {
+				return fmt.Errorf("backup before repairing failed (%w)", err)
+			}
+
+			if err := maintenance.Repair(problems...); err != nil {
+				return err
+			}
+
+			fmt.Printf("repaired %d problems, previous data saved in %s\n", len(problems), path)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "only report what would be removed")
+	return cmd
+}
+
+func newRekeyCmd(version string, c *config.Config, dbs ...*database.DB) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:   "rekey [flags]",
+		Short: "encrypt, decrypt or rotate the database encryption key",
+		Long: "re-encrypt the databases with a new key taken from a keyfile or passphrase. " +
+			"Unencrypted databases are converted in place; update the [encryption] config afterwards.",
+		Example: "db rekey --generate ~/.pomo/key",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			saltPath, err := c.Encryption.SaltPath()
+			if err != nil {
+				return err
+			}
+
+			var key, salt []byte
+			switch {
+			case opts.decrypt:
+			case opts.generate != "":
+				path, err := config.ExpandPath(opts.generate)
+				if err != nil {
+					return err
+				}
+				if key, err = keys.Generate(path); err != nil {
+					return err
+				}
+			case opts.keyfile != "":
+				path, err := config.ExpandPath(opts.keyfile)
+				if err != nil {
+					return err
+				}
+				if key, err = keys.FromFile(path); err != nil {
+					return err
+				}
+			default:
+				passphrase, err := keys.Passphrase(keys.EnvNewPassphrase, "new passphrase: ", true)
+				if err != nil {
+					return err
+				}
+
+				// a fresh salt per passphrase, kept aside until every
+				// database has been rekeyed
+				salt = make([]byte, 16)
+				if _, err := rand.Read(salt); err != nil {
+					return err
+				}
+				if key, err = keys.Derive(passphrase, salt); err != nil {
+					return err
+				}
+			}
+
+			// the new salt is on disk before any database depends on it
+			pending := saltPath + ".new"
+			if salt != nil {
+				if err := os.WriteFile(pending, salt, 0600); err != nil {
+					return err
+				}
+			}
+
+			wasEncrypted := dbs[0].Encrypted()
+			if err := database.RekeyAll(key, dbs...); err != nil {
+				os.Remove(pending)
+				return fmt.Errorf("rekey failed, the databases keep their previous key (%w)", err)
+			}
+			for _, db := range dbs {
+				fmt.Printf("%s: rekeyed\n", db.Name)
+			}
+
+			if salt != nil {
+				if err := os.Rename(pending, saltPath); err != nil {
+					return fmt.Errorf("the databases use the salt in %s, move it to %s (%w)", pending, saltPath, err)
+				}
+			}
+
+			switch {
+			case opts.decrypt:
+				fmt.Println("set encryption.enabled = false in your config")
+			case opts.generate != "" || opts.keyfile != "":
+				fmt.Printf("set encryption.enabled = true and encryption.keyfile = %q in your config\n", opts.keyfile+opts.generate)
+			default:
+				fmt.Println("set encryption.enabled = true and leave encryption.keyfile empty in your config")
+			}
+			if wasEncrypted {
+				fmt.Println("backups taken before this point can only be restored with the previous key")
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.keyfile, "new-keyfile", "", "file holding the new key (raw or hex)")
+	cmd.PersistentFlags().StringVar(&opts.generate, "generate", "", "generate a random key into this file and use it")
+	cmd.PersistentFlags().BoolVar(&opts.decrypt, "decrypt", false, "remove encryption")
+	return cmd
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aelnahas/pomo/archive"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	format string
+	output string
+}
+
+func NewCmd(version string, store task.Store, sessionStore sessions.Store) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "export [flags]",
+		Short:   "export tasks, session history and cycle state",
+		Long:    "export tasks, session history and cycle state as json, csv (one file per entity) or a markdown report",
+		Example: "export --format csv --output ./pomo-export",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doc, err := archive.Build(store, sessionStore)
+			if err != nil {
+				return err
+			}
+
+			if opts.format == "csv" {
+				if opts.output == "" {
+					return fmt.Errorf("csv export requires --output <dir>")
+				}
+				return doc.WriteCSV(opts.output)
+			}
+
+			var w io.Writer = os.Stdout
+			if opts.output != "" {
+				f, err := os.Create(opts.output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			switch opts.format {
+			case "json":
+				return doc.WriteJSON(w)
+			case "markdown", "md":
+				return doc.WriteMarkdown(w)
+			default:
+				return fmt.Errorf("unknown format %s", opts.format)
+			}
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.format, "format", "f", "json", "export format: json, csv or markdown")
+	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", "", "output file (directory for csv), defaults to stdout")
+	return cmd
+}
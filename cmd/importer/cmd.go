@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aelnahas/pomo/archive"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	strategy string
+}
+
+func NewCmd(version string, store task.Store, sessionStore sessions.Store) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "import <file> [flags]",
+		Short:   "import a json export",
+		Long:    "import a json export, resolving tasks that already exist with the given strategy",
+		Example: "import pomo.json --strategy merge",
+		Args:    cobra.ExactArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			strategy, err := archive.ParseStrategy(opts.strategy)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			doc, err := archive.ReadJSON(f)
+			if err != nil {
+				return err
+			}
+
+			summary, err := archive.Import(doc, store, sessionStore, strategy)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("added %d, updated %d, skipped %d\n", summary.Added, summary.Updated, summary.Skipped)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.strategy, "strategy", "s", string(archive.Skip), "conflict strategy: skip, overwrite or merge")
+	return cmd
+}
@@ -0,0 +1,61 @@
+package restore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aelnahas/pomo/backup"
+	"github.com/aelnahas/pomo/database"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	verify bool
+}
+
+func NewCmd(version, pomoVersion, backupDir string, dbs ...*database.DB) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "restore <file> [flags]",
+		Short:   "restore the databases from a snapshot",
+		Long:    "verify a snapshot and replace the task and session databases with its contents, taking a safety snapshot first",
+		Args:    cobra.ExactArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.verify {
+				manifest, _, cleanup, err := backup.Verify(args[0])
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+
+				fmt.Printf("%s: ok, created %s by %s\n", args[0], manifest.CreatedAt.Format(time.RFC1123), manifest.PomoVersion)
+				for _, db := range manifest.Databases {
+					fmt.Printf("  %s: schema v%d, %d bytes\n", db.Name, db.SchemaVersion, db.Size)
+				}
+				return nil
+			}
+
+			safety, err := backup.Create(backupDir, pomoVersion, dbs...)
+			if err != nil {
+				return fmt.Errorf("safety snapshot failed (%w)", err)
+			}
+
+			if _, err := backup.Restore(args[0], dbs...); err != nil {
+				return fmt.Errorf("%w, previous data saved in %s", err, safety)
+			}
+
+			for _, db := range dbs {
+				if _, err := db.Migrate(backupDir); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("restored %s, previous data saved in %s\n", args[0], safety)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().BoolVar(&opts.verify, "verify", false, "only verify the snapshot")
+	return cmd
+}
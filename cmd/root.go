@@ -6,15 +6,30 @@ import (
 	"io"
 	"os"
 
+	"github.com/aelnahas/pomo/backup"
 	"github.com/aelnahas/pomo/build"
 	"github.com/aelnahas/pomo/cmd/add"
+	cmdbackup "github.com/aelnahas/pomo/cmd/backup"
 	"github.com/aelnahas/pomo/cmd/config"
+	"github.com/aelnahas/pomo/cmd/db"
+	"github.com/aelnahas/pomo/cmd/export"
+	"github.com/aelnahas/pomo/cmd/importer"
 	"github.com/aelnahas/pomo/cmd/list"
 	"github.com/aelnahas/pomo/cmd/remove"
+	"github.com/aelnahas/pomo/cmd/restore"
+	"github.com/aelnahas/pomo/cmd/serve"
 	"github.com/aelnahas/pomo/cmd/set"
+	"github.com/aelnahas/pomo/cmd/stats"
+	"github.com/aelnahas/pomo/cmd/taskwarrior"
 	"github.com/aelnahas/pomo/cmd/timer"
+	"github.com/aelnahas/pomo/cmd/todotxt"
 	"github.com/aelnahas/pomo/cmd/version"
+	cmdwebhooks "github.com/aelnahas/pomo/cmd/webhooks"
+	"github.com/aelnahas/pomo/database"
+	"github.com/aelnahas/pomo/events"
 	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/sound"
+	"github.com/aelnahas/pomo/synclog"
 	"github.com/aelnahas/pomo/task"
 	"github.com/spf13/cobra"
 )
@@ -23,7 +38,10 @@ type options struct {
 	init bool
 }
 
-func NewRootCmd() (*cobra.Command, error) {
+// NewRootCmd builds every command without opening the databases. The
+// returned open function opens them, asking for the encryption key if it
+// has to, and must be called before running a command that uses them.
+func NewRootCmd() (*cobra.Command, func() error, func(), error) {
 	opts := options{}
 	formattedVersion := version.Format(build.Version, build.Date)
 	rootCmd := &cobra.Command{
@@ -69,52 +87,279 @@ func NewRootCmd() (*cobra.Command, error) {
 
 	appConfig, err := config.Parse(config.DefaultPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	taskPath, err := config.ExpandPath(appConfig.Database.Task)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	store, err := task.NewStore(taskPath)
+	sessionPath, err := config.ExpandPath(appConfig.Database.Session)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	sessionPath, err := config.ExpandPath(appConfig.Database.Session)
+	runner, err := appConfig.Hooks.Runner()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
+	}
+
+	sender, err := appConfig.Webhooks.Sender()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	player, err := appConfig.Sound.NewPlayer()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	backupDir, err := appConfig.Database.BackupPath()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the commands are built around stand-ins, open fills them in
+	tasksDB, sessionsDB := &database.DB{}, &database.DB{}
+	dbs := []*database.DB{tasksDB, sessionsDB}
+	tasks, sessionStore := &lazyTasks{}, &lazySessions{}
+	bus := events.NewBus()
+
+	var (
+		store   task.Store
+		syncLog *synclog.Log
+	)
+	stops := make([]func(), 0, 3)
+	opened := false
+	open := func() error {
+		if appConfig.Encryption.Enabled && (appConfig.Sync.Dir != "" || sender.Enabled()) {
+			return fmt.Errorf("sync and webhooks write task titles in plaintext, turn them off to use encryption")
+		}
+
+		key, err := appConfig.Encryption.Key()
+		if err != nil {
+			return err
+		}
+
+		for _, d := range []struct {
+			db         *database.DB
+			name, path string
+			migrations []database.Migration
+		}{
+			{tasksDB, "tasks", taskPath, task.Migrations},
+			{sessionsDB, "sessions", sessionPath, sessions.Migrations},
+		} {
+			db, err := database.Open(d.name, d.path, key, d.migrations)
+			if err != nil {
+				return err
+			}
+			*d.db = *db
+			opened = true
+		}
+
+		store = task.NewStoreOn(tasksDB)
+		tasks.Store = store
+		sessionStore.Store = sessions.NewStoreOn(sessionsDB, appConfig.Timers.Interval)
+
+		if appConfig.Sync.Dir != "" {
+			syncDir, err := config.ExpandPath(appConfig.Sync.Dir)
+			if err != nil {
+				return err
+			}
+
+			device, err := appConfig.Sync.DeviceName()
+			if err != nil {
+				return err
+			}
+
+			syncLog, err = synclog.Open(syncDir, device, tasksDB)
+			if err != nil {
+				return err
+			}
+			tasks.Store = synclog.Wrap(store, syncLog)
+		}
+
+		if runner.Enabled() {
+			stops = append(stops, runner.Watch(bus))
+		}
+		if sender.Enabled() {
+			stops = append(stops, sender.Watch(bus))
+		}
+		if appConfig.Sound.Enabled {
+			stops = append(stops, sound.Watch(bus, player, appConfig.Sound.Ticking))
+		}
+		return nil
+	}
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if !opened {
+			return nil
+		}
+
+		for _, d := range dbs {
+			if _, err := d.Migrate(backupDir); err != nil {
+				return err
+			}
+		}
+
+		if syncLog != nil {
+			if _, err := syncLog.Snapshot(store); err != nil {
+				return fmt.Errorf("sync failed (%w)", err)
+			}
+
+			_, skipped, err := syncLog.Merge(store)
+			for _, line := range skipped {
+				fmt.Fprintf(os.Stderr, "sync: skipped unreadable op at %s\n", line)
+			}
+			if err != nil {
+				return fmt.Errorf("sync failed (%w)", err)
+			}
+		}
+
+		if appConfig.Backup.Auto {
+			if _, err := backup.Daily(backupDir, build.Version, appConfig.Backup.Keep, dbs...); err != nil {
+				return fmt.Errorf("automatic backup failed (%w)", err)
+			}
+		}
+		return nil
+	}
+
+	rootCmd.SetVersionTemplate(formattedVersion)
+	rootCmd.AddCommand(offline(version.NewCmd(build.Version, build.Date)))
+	rootCmd.AddCommand(add.NewCmd(formattedVersion, tasks, bus))
+	rootCmd.AddCommand(set.NewCmd(formattedVersion, tasks, bus))
+	rootCmd.AddCommand(timer.NewCmd(formattedVersion, appConfig, tasks, sessionStore, bus, sender))
+	rootCmd.AddCommand(offline(config.NewCmd(formattedVersion, appConfig)))
+	rootCmd.AddCommand(list.NewCmd(formattedVersion, tasks))
+	rootCmd.AddCommand(remove.NewCmd(formattedVersion, tasks))
+	rootCmd.AddCommand(export.NewCmd(formattedVersion, tasks, sessionStore))
+	rootCmd.AddCommand(importer.NewCmd(formattedVersion, tasks, sessionStore))
+	rootCmd.AddCommand(todotxt.NewCmd(formattedVersion, appConfig, tasks))
+	rootCmd.AddCommand(taskwarrior.NewCmd(formattedVersion, tasks))
+	rootCmd.AddCommand(serve.NewCmd(formattedVersion, appConfig, tasks, sessionStore, bus, sender))
+	rootCmd.AddCommand(cmdbackup.NewCmd(formattedVersion, build.Version, backupDir, dbs...))
+	rootCmd.AddCommand(restore.NewCmd(formattedVersion, build.Version, backupDir, dbs...))
+	rootCmd.AddCommand(db.NewCmd(formattedVersion, appConfig, backupDir, tasksDB, sessionsDB))
+	rootCmd.AddCommand(cmdwebhooks.NewCmd(formattedVersion, sender))
+	rootCmd.AddCommand(stats.NewCmd(formattedVersion, tasks, sessionStore))
+	rootCmd.PersistentFlags().BoolVar(&opts.init, "init", false, "initialize default config")
+	cleanup := func() {
+		for _, stop := range stops {
+			stop()
+		}
+		if c, ok := player.(io.Closer); ok {
+			c.Close()
+		}
+		for _, d := range dbs {
+			if d.DB == nil {
+				continue
+			}
+			if err := d.Tidy(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", d.Name, err)
+			}
+			if err := d.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", d.Name, err)
+			}
+		}
+	}
+
+	return rootCmd, open, cleanup, nil
+}
+
+// lazyTasks and lazySessions stand in for the stores until the databases
+// are opened, which only happens for commands that use them.
+type lazyTasks struct{ task.Store }
+
+type lazySessions struct{ sessions.Store }
+
+// offlineAnnotation marks commands that never touch the databases, so
+// running them does not ask for the encryption passphrase.
+const offlineAnnotation = "offline"
+
+func offline(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
 	}
+	cmd.Annotations[offlineAnnotation] = "true"
+	return cmd
+}
 
-	sessionStore, err := sessions.NewStore(sessionPath, appConfig.Timers.Interval)
+// usesDatabases reports whether running cmd with args needs the databases:
+// help, versions, the config file and --init do not.
+func usesDatabases(cmd *cobra.Command, args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch arg {
+		case "-h", "--help", "-v", "--version":
+			return false
+		}
+	}
+
+	// the root command only prints help or writes the config with --init
+	if !cmd.HasParent() {
+		return false
+	}
+
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[offlineAnnotation] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRemoteCmd is the root command for when another pomo process, a
+// running timer or pomo serve, holds the databases. Only the timer
+// commands that go through its api can run.
+func NewRemoteCmd() (*cobra.Command, error) {
+	formattedVersion := version.Format(build.Version, build.Date)
+	appConfig, err := config.Parse(config.DefaultPath)
 	if err != nil {
 		return nil, err
 	}
 
+	rootCmd := &cobra.Command{
+		Use:          "pomo <command> <subcommand> [flags]",
+		Short:        "pomodoro cli",
+		Long:         "simple todo list with pomodoro timer tool",
+		Version:      formattedVersion,
+		SilenceUsage: true,
+	}
 	rootCmd.SetVersionTemplate(formattedVersion)
 	rootCmd.AddCommand(version.NewCmd(build.Version, build.Date))
-	rootCmd.AddCommand(add.NewCmd(formattedVersion, store))
-	rootCmd.AddCommand(set.NewCmd(formattedVersion, store))
-	rootCmd.AddCommand(timer.NewCmd(formattedVersion, appConfig, store, sessionStore))
-	rootCmd.AddCommand(config.NewCmd(formattedVersion, appConfig))
-	rootCmd.AddCommand(list.NewCmd(formattedVersion, store))
-	rootCmd.AddCommand(remove.NewCmd(formattedVersion, store))
-	rootCmd.PersistentFlags().BoolVar(&opts.init, "init", false, "initialize default config")
+	rootCmd.AddCommand(timer.NewRemoteCmd(formattedVersion, appConfig))
 	return rootCmd, nil
 }
 
 func Execute() {
-	rootCmd, err := NewRootCmd()
+	rootCmd, open, cleanup, err := NewRootCmd()
+	if err == nil {
+		if target, _, findErr := rootCmd.Find(os.Args[1:]); findErr == nil && usesDatabases(target, os.Args[1:]) {
+			err = open()
+		}
+	}
+	if errors.Is(err, database.ErrLocked) {
+		if remote, remoteErr := NewRemoteCmd(); remoteErr == nil {
+			if found, _, findErr := remote.Find(os.Args[1:]); findErr == nil && found.Runnable() {
+				cleanup()
+				rootCmd, cleanup, err = remote, func() {}, nil
+			}
+		}
+	}
 	if err != nil {
 		fmt.Printf("%s\n", err.Error())
+		if cleanup != nil {
+			cleanup()
+		}
 		os.Exit(2)
 	}
 
-	if rootCmd.Execute(); err != nil {
-		fmt.Printf("%s\n", err.Error())
+	err = rootCmd.Execute()
+	cleanup()
+	if err != nil {
 		os.Exit(1)
-	} else {
-		os.Exit(0)
 	}
 }
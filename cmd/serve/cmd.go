@@ -0,0 +1,67 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aelnahas/pomo/api"
+	"github.com/aelnahas/pomo/cmd/config"
+	"github.com/aelnahas/pomo/cmd/timer"
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/aelnahas/pomo/webhooks"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	listen string
+}
+
+func NewCmd(version string, c *config.Config, store task.Store, sessionStore sessions.Store, bus *events.Bus, sender *webhooks.Sender) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "serve [flags]",
+		Short:   "serve the local http/json api and web dashboard",
+		Long:    "serve the local http/json api and web dashboard. While it runs it holds the databases, timer start then starts the timer in it and the timer's commands drive that timer",
+		Example: "serve --listen 127.0.0.1:7070",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen := opts.listen
+			if listen == "" {
+				listen = c.API.Listen
+			}
+			if listen == "" {
+				listen = api.DefaultListen
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if sender.Enabled() {
+				defer sender.Deliver()()
+			}
+
+			server := api.NewServer(store, sessionStore, c.Timers.Interval, bus)
+			launcher, err := timer.NewLauncher(ctx, c, store, sessionStore, bus, server)
+			if err != nil {
+				return err
+			}
+			defer launcher.Stop()
+			server.Launch(launcher)
+
+			l, err := api.Listen(listen)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("listening on http://%s\n", listen)
+			return server.Serve(ctx, l)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.listen, "listen", "l", "", "loopback address to listen on, defaults to api.listen")
+	return cmd
+}
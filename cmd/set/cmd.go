@@ -1,6 +1,7 @@
 package set
 
 import (
+	"github.com/aelnahas/pomo/events"
 	"github.com/aelnahas/pomo/output"
 	"github.com/aelnahas/pomo/task"
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ type options struct {
 	current  bool
 }
 
-func NewCmd(version string, store task.Store) *cobra.Command {
+func NewCmd(version string, store task.Store, bus *events.Bus) *cobra.Command {
 	opts := options{}
 	cmd := &cobra.Command{
 		Use:     "set <title-id> [flags]",
@@ -41,6 +42,7 @@ func NewCmd(version string, store task.Store) *cobra.Command {
 					return err
 				}
 
+				bus.Publish(events.Event{Type: events.TaskCompleted, Task: updated})
 				output.Printlist(*updated)
 			}
 			return nil
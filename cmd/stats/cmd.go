@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aelnahas/pomo/output"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	days int
+}
+
+func NewCmd(version string, store task.Store, sessionStore sessions.Store) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "stats [flags]",
+		Short:   "report interruptions per session and per task",
+		Long:    "report the interruptions logged with i and e during sessions, for each session and totalled for each task",
+		Example: "stats --days 30",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.days < 0 {
+				return fmt.Errorf("days must not be negative")
+			}
+
+			history, err := sessionStore.History()
+			if err != nil {
+				return err
+			}
+
+			since := time.Now().AddDate(0, 0, -opts.days)
+			records := make([]sessions.Record, 0, len(history))
+			for _, r := range history {
+				if opts.days == 0 || r.StartedAt.After(since) {
+					records = append(records, r)
+				}
+			}
+
+			tasks, err := store.List(func(t task.Task) bool {
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			titles := make(map[uuid.UUID]string, len(tasks))
+			for _, t := range tasks {
+				titles[t.ID] = t.Title
+			}
+
+			output.PrintSessionInterruptions(titles, records...)
+			fmt.Println()
+			output.PrintTaskInterruptions(titles, records...)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().IntVarP(&opts.days, "days", "d", 7, "only count sessions started in the last n days, 0 for all of them")
+	return cmd
+}
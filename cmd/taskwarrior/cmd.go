@@ -0,0 +1,101 @@
+package taskwarrior
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aelnahas/pomo/task"
+	"github.com/aelnahas/pomo/taskwarrior"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	all bool
+}
+
+func NewCmd(version string, store task.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "taskwarrior <command> [flags]",
+		Aliases: []string{"tw"},
+		Short:   "import and export taskwarrior json",
+		Version: version,
+	}
+
+	cmd.AddCommand(newImportCmd(version, store))
+	cmd.AddCommand(newExportCmd(version, store))
+	return cmd
+}
+
+func newImportCmd(version string, store task.Store) *cobra.Command {
+	return &cobra.Command{
+		Use:     "import <file|->",
+		Short:   "import the output of `task export`",
+		Example: "task export | pomo taskwarrior import -",
+		Args:    cobra.ExactArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var r io.Reader = os.Stdin
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+
+			tasks, err := taskwarrior.Read(r)
+			if err != nil {
+				return err
+			}
+
+			summary, err := taskwarrior.Import(store, tasks...)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("added %d, updated %d, skipped %d\n", summary.Added, summary.Updated, summary.Skipped)
+			return nil
+		},
+	}
+}
+
+func newExportCmd(version string, store task.Store) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "export [file] [flags]",
+		Short:   "export tasks as taskwarrior json",
+		Example: "pomo taskwarrior export | task import",
+		Args:    cobra.MaximumNArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, err := store.List(func(t task.Task) bool {
+				return opts.all || t.Status != task.Complete
+			})
+			if err != nil {
+				return err
+			}
+
+			exported := make([]taskwarrior.Task, 0, len(tasks))
+			for _, t := range tasks {
+				exported = append(exported, taskwarrior.FromTask(t))
+			}
+
+			var w io.Writer = os.Stdout
+			if len(args) == 1 {
+				f, err := os.Create(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			return taskwarrior.Write(w, exported...)
+		},
+	}
+
+	cmd.PersistentFlags().BoolVarP(&opts.all, "all", "a", false, "include completed tasks")
+	return cmd
+}
@@ -1,23 +1,45 @@
 package timer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/aelnahas/pomo/api"
 	"github.com/aelnahas/pomo/cmd/config"
 	"github.com/aelnahas/pomo/countdown"
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/notify"
 	"github.com/aelnahas/pomo/output"
 	"github.com/aelnahas/pomo/sessions"
 	"github.com/aelnahas/pomo/task"
+	"github.com/aelnahas/pomo/webhooks"
 	"github.com/spf13/cobra"
 )
 
+// errStopped ends the timer without an error once a session is cut short
+// on purpose.
+var errStopped = errors.New("session stopped")
+
 type options struct {
-	reset bool
-	show  bool
+	reset  bool
+	show   bool
+	listen string
+	plain  bool
+	flow   bool
+
+	auto    bool
+	confirm bool
+	cycles  int
 }
 
-func NewCmd(version string, config *config.Config, store task.Store, sessionStore sessions.Store) *cobra.Command {
+func NewCmd(version string, config *config.Config, store task.Store, sessionStore sessions.Store, bus *events.Bus, sender *webhooks.Sender) *cobra.Command {
 	opts := options{}
 	cmd := &cobra.Command{
 		Use:     "timer <command> [flags]",
@@ -42,55 +64,430 @@ func NewCmd(version string, config *config.Config, store task.Store, sessionStor
 		},
 	}
 
-	cmd.AddCommand(newStartCmd(version, config, store, sessionStore))
+	cmd.AddCommand(newStartCmd(version, config, store, sessionStore, bus, sender))
+	cmd.AddCommand(controlCmds(version, config, false)...)
 	cmd.PersistentFlags().BoolVarP(&opts.reset, "reset", "r", false, "reset sessions")
 	cmd.PersistentFlags().BoolVarP(&opts.show, "show", "s", false, "show sessions")
 	return cmd
 }
 
-func newStartCmd(version string, config *config.Config, store task.Store, sessionStore sessions.Store) *cobra.Command {
-	return &cobra.Command{
+func newStartCmd(version string, config *config.Config, store task.Store, sessionStore sessions.Store, bus *events.Bus, sender *webhooks.Sender) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
 		Use:     "start",
 		Short:   "start a timer",
+		Long:    "start a timer for the current session, when it ends a notification offers to start the next one or snooze. With --flow a focus session counts up until you finish it, earning credit and a break in proportion to the time spent. With --auto each session follows the last, focus then its break, until stopped or --cycles focus sessions and their breaks are done",
 		Version: version,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			current, err := store.GetCurrentTask()
-			if err != nil {
-				return fmt.Errorf("current task is not set (%w)", err)
+			if sender.Enabled() {
+				defer sender.Deliver()()
 			}
 
-			var duration time.Duration
-			sessionType, err := sessionStore.Current()
+			var server *api.Server
+			if opts.listen != "" {
+				server = api.NewServer(store, sessionStore, config.Timers.Interval, bus)
+
+				l, err := api.Listen(opts.listen)
+				if err != nil {
+					return err
+				}
+
+				ctx, cancel := context.WithCancel(cmd.Context())
+				defer cancel()
+				go func() {
+					if err := server.Serve(ctx, l); err != nil {
+						fmt.Fprintf(os.Stderr, "api server stopped (%s)\n", err)
+					}
+				}()
+			}
+
+			notifier, err := config.Notifications.Notifier()
 			if err != nil {
 				return err
 			}
-
-			switch sessionType {
-			case sessions.Focus:
-				duration = config.Timers.FocusDuration()
-			case sessions.Short:
-				duration = config.Timers.ShortBreakDuration()
-			default:
-				duration = config.Timers.LongBreakDuration()
+			if c, ok := notifier.(io.Closer); ok {
+				defer c.Close()
 			}
 
-			if err := countdown.New(duration, current, sessionType).Run(); err != nil {
+			wait, snooze, err := config.Notifications.Durations()
+			if err != nil {
 				return err
 			}
 
-			if sessionType == sessions.Focus {
-				current, err = store.AddSessions(current.ID)
+			focused := 0
+			for {
+				current, finished, err := runSession(config, store, sessionStore, bus, server, screen(opts), opts.flow)
+				if errors.Is(err, errStopped) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				output.Printlist(*current)
+
+				next, err := sessionStore.Current()
 				if err != nil {
 					return err
 				}
-			}
 
-			if err := sessionStore.Increment(); err != nil {
-				return err
+				if finished == sessions.Focus {
+					focused++
+				}
+
+				if opts.auto {
+					if opts.cycles > 0 && focused >= opts.cycles && next == sessions.Focus {
+						fmt.Printf("done %d of %d cycles\n", focused, opts.cycles)
+						return nil
+					}
+
+					n := ended(current, finished, next, snooze)
+					again, err := advance(cmd.Context(), notifier, n, opts.confirm, screen(opts), snooze)
+					if err != nil {
+						return err
+					}
+					if !again {
+						return nil
+					}
+					continue
+				}
+
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+				again := await(ctx, notifier, ended(current, finished, next, snooze), wait, snooze, server)
+				stop()
+				if !again {
+					return nil
+				}
 			}
+		},
+	}
 
-			output.Printlist(*current)
-			return nil
+	cmd.PersistentFlags().StringVarP(&opts.listen, "listen", "l", "", "serve the http api, including the running timer, on this loopback address")
+	cmd.PersistentFlags().BoolVar(&opts.plain, "plain", false, "draw with plain ansi escapes instead of taking over the terminal")
+	cmd.PersistentFlags().BoolVar(&opts.flow, "flow", false, "count focus sessions up with no fixed end, press f to finish")
+	cmd.PersistentFlags().BoolVar(&opts.auto, "auto", config.Auto.Enabled, "start each session when the last one ends")
+	cmd.PersistentFlags().BoolVar(&opts.confirm, "confirm", config.Auto.Confirm, "with --auto, wait for a key before each session starts")
+	cmd.PersistentFlags().IntVar(&opts.cycles, "cycles", config.Auto.Cycles, "with --auto, stop after this many focus sessions and their breaks, 0 for no limit")
+	return cmd
+}
+
+func screen(opts options) countdown.Renderer {
+	if opts.plain {
+		return countdown.NewANSI(os.Stdout, os.Stdin)
+	}
+	return countdown.NewTermbox()
+}
+
+// runSession counts down the current session and records it, returning the
+// current task and the type of session that finished. A flow focus session
+// counts up instead, and is credited and followed by a break in proportion
+// to its length.
+func runSession(config *config.Config, store task.Store, sessionStore sessions.Store, bus *events.Bus, server *api.Server, screen countdown.Renderer, flow bool) (*task.Task, sessions.Type, error) {
+	current, err := store.GetCurrentTask()
+	if err != nil {
+		return nil, "", fmt.Errorf("current task is not set (%w)", err)
+	}
+
+	var duration time.Duration
+	session, err := sessionStore.Session()
+	if err != nil {
+		return nil, "", err
+	}
+	sessionType := session.Current
+	flow = flow && sessionType == sessions.Focus
+
+	switch sessionType {
+	case sessions.Focus:
+		duration = config.Timers.FocusDuration()
+	case sessions.Short:
+		duration = config.Timers.ShortBreakDuration()
+	default:
+		duration = config.Timers.LongBreakDuration()
+	}
+	if sessionType != sessions.Focus && session.Break > 0 {
+		duration = session.Break
+	}
+
+	timer := countdown.New(duration, current, sessionType, bus, screen)
+	timer.AbandonAfter(config.Timers.MaxPauseDuration())
+	timer.ExtendBy(config.Timers.ExtendDuration())
+	if flow {
+		timer.CountUp()
+	}
+	if server != nil {
+		server.Attach(timer)
+		defer server.Attach(nil)
+	}
+
+	record := sessions.NewRecord(sessionType, current.ID, time.Now())
+	err = timer.Run()
+	if errors.Is(err, countdown.ErrInterrupted) {
+		fmt.Println("session voided, nothing was recorded")
+		return nil, "", errStopped
+	}
+	skipped := errors.Is(err, countdown.ErrSkipped)
+	if err != nil && !skipped && !errors.Is(err, countdown.ErrAbandoned) {
+		return nil, "", err
+	}
+
+	record.Pauses = timer.Pauses()
+	record.Interruptions = timer.Interruptions()
+	record.Extensions = timer.Extensions()
+	record.Skipped = skipped
+	record.Abandoned = err != nil && !skipped
+	record.End(time.Now())
+	if err := sessionStore.Record(*record); err != nil {
+		return nil, "", err
+	}
+
+	if record.Abandoned {
+		return abandon(config, store, bus, current, sessionType, record.Duration, err)
+	}
+
+	if flow {
+		return credit(config, store, sessionStore, bus, current, timer.Elapsed())
+	}
+
+	if sessionType == sessions.Focus {
+		current, err = store.AddSessions(current.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		bus.Publish(events.Event{Type: events.TaskChanged, Task: current})
+	}
+
+	if err := sessionStore.Increment(); err != nil {
+		return nil, "", err
+	}
+
+	return current, sessionType, nil
+}
+
+// abandon credits the task with what its abandoned focus session is worth
+// and leaves the cycle where it is, so the session comes round again. A
+// session abandoned on purpose stops the timer without an error.
+func abandon(config *config.Config, store task.Store, bus *events.Bus, current *task.Task, sessionType sessions.Type, ran time.Duration, reason error) (*task.Task, sessions.Type, error) {
+	var pomodoros float64
+	if sessionType == sessions.Focus {
+		var err error
+		pomodoros, err = config.Partial.Pomodoros(ran, config.Timers.FocusDuration())
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if pomodoros > 0 {
+		credited, err := store.Credit(current.ID, pomodoros)
+		if err != nil {
+			return nil, "", err
+		}
+		bus.Publish(events.Event{Type: events.TaskChanged, Task: credited})
+	}
+
+	if sessionType == sessions.Focus {
+		fmt.Printf("focus session abandoned after %s, credited %.2f pomodoros\n", ran.Round(time.Second), pomodoros)
+	} else {
+		fmt.Printf("%s abandoned after %s\n", describe(sessionType), ran.Round(time.Second))
+	}
+
+	// pausing for too long wraps ErrAbandoned, it was not a choice
+	if reason != countdown.ErrAbandoned {
+		return nil, "", reason
+	}
+	return nil, "", errStopped
+}
+
+// credit closes a flow session that ran for elapsed: the task gets its
+// share of pomodoros and the next break the length it earned.
+func credit(config *config.Config, store task.Store, sessionStore sessions.Store, bus *events.Bus, current *task.Task, elapsed time.Duration) (*task.Task, sessions.Type, error) {
+	pomodoros, err := config.Flow.Pomodoros(elapsed, config.Timers.FocusDuration())
+	if err != nil {
+		return nil, "", err
+	}
+
+	if pomodoros > 0 {
+		current, err = store.Credit(current.ID, pomodoros)
+		if err != nil {
+			return nil, "", err
+		}
+		bus.Publish(events.Event{Type: events.TaskChanged, Task: current})
+	}
+
+	if err := sessionStore.Increment(); err != nil {
+		return nil, "", err
+	}
+
+	session, err := sessionStore.Session()
+	if err != nil {
+		return nil, "", err
+	}
+	session.Break = config.Flow.Break(elapsed)
+	if err := sessionStore.SetSession(*session); err != nil {
+		return nil, "", err
+	}
+
+	fmt.Printf("flowed for %s, credited %.2f pomodoros, next up: %s of %s\n",
+		elapsed.Round(time.Second), pomodoros, describe(session.Current), session.Break)
+	return current, sessions.Focus, nil
+}
+
+func ended(current *task.Task, finished, next sessions.Type, snooze time.Duration) notify.Notification {
+	summary := "break is over"
+	if finished == sessions.Focus {
+		summary = "focus session complete"
+	}
+
+	start := "Start break"
+	if next == sessions.Focus {
+		start = "Start focus"
+	}
+
+	return notify.Notification{
+		Summary: summary,
+		Body:    fmt.Sprintf("%s, next up: %s", current.Title, describe(next)),
+		Actions: []notify.Action{
+			{Key: notify.StartNext, Label: start},
+			{Key: notify.Snooze, Label: fmt.Sprintf("Snooze %s", shortDuration(snooze))},
 		},
 	}
 }
+
+// advance moves an --auto run on to the next session, reporting whether to
+// start it. n is announced without actions, the answer, when confirm asks
+// for one, is given on screen: enter starts the session, z snoozes the
+// announcement and q or esc stops.
+func advance(ctx context.Context, notifier notify.Notifier, n notify.Notification, confirm bool, screen countdown.Renderer, snooze time.Duration) (bool, error) {
+	n.Actions = nil
+	announce := func() {
+		if _, err := notifier.Notify(ctx, n); err != nil {
+			fmt.Fprintf(os.Stderr, "notification failed (%s)\n", err)
+		}
+	}
+
+	announce()
+	if !confirm {
+		return true, nil
+	}
+
+	question := []string{n.Summary, n.Body, "", fmt.Sprintf("enter start  z snooze %s  q stop", shortDuration(snooze))}
+	var wait time.Duration
+	for {
+		key, err := countdown.Ask(screen, question, wait,
+			countdown.KeyEnter, 'z', 'Z', 'q', 'Q', countdown.KeyEsc, countdown.KeyCtrlC)
+		if err != nil {
+			return false, err
+		}
+
+		switch key {
+		case countdown.KeyEnter:
+			return true, nil
+		case 'z', 'Z':
+			wait = snooze
+			question[2] = fmt.Sprintf("snoozed until %s", time.Now().Add(snooze).Format("15:04:05"))
+		case 0:
+			announce()
+			wait = 0
+			question[2] = ""
+		default:
+			return false, nil
+		}
+	}
+}
+
+// alarm lets the api snooze a finished session's notification.
+type alarm chan struct{}
+
+func (a alarm) Snooze() error {
+	select {
+	case a <- struct{}{}:
+		return nil
+	default:
+		return errors.New("already snoozed")
+	}
+}
+
+// await shows n and reports whether the next session should start. A
+// snooze, from the notification or the api, shows the notification again
+// once the snooze is over. A notifier without actions cannot be answered,
+// so n is only shown and the timer stops.
+func await(ctx context.Context, notifier notify.Notifier, n notify.Notification, wait, snooze time.Duration, server *api.Server) bool {
+	if !notifier.Actions() {
+		n.Actions = nil
+		if _, err := notifier.Notify(ctx, n); err != nil {
+			fmt.Fprintf(os.Stderr, "notification failed (%s)\n", err)
+		}
+		return false
+	}
+
+	for {
+		fmt.Printf("waiting up to %s for a notification action, ctrl-c to stop\n", wait)
+
+		action, err := ring(ctx, notifier, n, wait, server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "notification failed (%s)\n", err)
+			return false
+		}
+
+		if action != notify.Snooze {
+			return action == notify.StartNext
+		}
+
+		fmt.Printf("snoozed for %s\n", snooze)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(snooze):
+		}
+	}
+}
+
+// ring shows n until it is answered, it times out or the api snoozes it.
+func ring(ctx context.Context, notifier notify.Notifier, n notify.Notification, wait time.Duration, server *api.Server) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	type answer struct {
+		action string
+		err    error
+	}
+	answered := make(chan answer, 1)
+	go func() {
+		action, err := notifier.Notify(ctx, n)
+		answered <- answer{action, err}
+	}()
+
+	snoozed := make(alarm, 1)
+	if server != nil {
+		server.Ring(snoozed)
+		defer server.Ring(nil)
+	}
+
+	select {
+	case a := <-answered:
+		return a.action, a.err
+	case <-snoozed:
+		cancel()
+		<-answered
+		return notify.Snooze, nil
+	}
+}
+
+func describe(sessionType sessions.Type) string {
+	switch sessionType {
+	case sessions.Short:
+		return "short break"
+	case sessions.Long:
+		return "long break"
+	}
+	return string(sessionType)
+}
+
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
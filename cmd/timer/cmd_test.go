@@ -0,0 +1,183 @@
+package timer
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aelnahas/pomo/api"
+	"github.com/aelnahas/pomo/notify"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+// unanswered is an answer that leaves the notification up until it times
+// out or is taken down.
+const unanswered = "unanswered"
+
+// fakeNotifier answers notifications with answers in turn, and with nothing
+// once they run out.
+type fakeNotifier struct {
+	actions bool
+
+	mu      sync.Mutex
+	answers []string
+	shown   []notify.Notification
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, n notify.Notification) (string, error) {
+	f.mu.Lock()
+	f.shown = append(f.shown, n)
+	answer := ""
+	if len(f.answers) > 0 {
+		answer, f.answers = f.answers[0], f.answers[1:]
+	}
+	f.mu.Unlock()
+
+	if answer == unanswered {
+		<-ctx.Done()
+		return "", nil
+	}
+	return answer, nil
+}
+
+func (f *fakeNotifier) Actions() bool {
+	return f.actions
+}
+
+func (f *fakeNotifier) notifications() []notify.Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]notify.Notification(nil), f.shown...)
+}
+
+func sessionEnded() notify.Notification {
+	return ended(&task.Task{Title: "write tests"}, sessions.Focus, sessions.Short, time.Minute)
+}
+
+func TestAwaitStartsNext(t *testing.T) {
+	notifier := &fakeNotifier{actions: true, answers: []string{notify.StartNext}}
+
+	if !await(context.Background(), notifier, sessionEnded(), time.Minute, time.Minute, nil) {
+		t.Error("start next did not start the next session")
+	}
+
+	shown := notifier.notifications()
+	if len(shown) != 1 {
+		t.Fatalf("shown %d notifications, want 1", len(shown))
+	}
+	if len(shown[0].Actions) != 2 || shown[0].Actions[0].Key != notify.StartNext || shown[0].Actions[1].Key != notify.Snooze {
+		t.Errorf("offered %+v, want start next and snooze", shown[0].Actions)
+	}
+}
+
+func TestAwaitDismissed(t *testing.T) {
+	notifier := &fakeNotifier{actions: true, answers: []string{""}}
+
+	if await(context.Background(), notifier, sessionEnded(), time.Minute, time.Minute, nil) {
+		t.Error("a dismissed notification started the next session")
+	}
+}
+
+func TestAwaitTimesOut(t *testing.T) {
+	notifier := &fakeNotifier{actions: true, answers: []string{unanswered}}
+
+	start := time.Now()
+	if await(context.Background(), notifier, sessionEnded(), 50*time.Millisecond, time.Minute, nil) {
+		t.Error("an unanswered notification started the next session")
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond || waited > 5*time.Second {
+		t.Errorf("waited %s for a 50ms timeout", waited)
+	}
+}
+
+func TestAwaitSnoozesFromNotification(t *testing.T) {
+	notifier := &fakeNotifier{actions: true, answers: []string{notify.Snooze, notify.StartNext}}
+
+	start := time.Now()
+	if !await(context.Background(), notifier, sessionEnded(), time.Minute, 50*time.Millisecond, nil) {
+		t.Error("start next after a snooze did not start the next session")
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("asked again after %s, before the snooze was over", waited)
+	}
+	if shown := notifier.notifications(); len(shown) != 2 {
+		t.Errorf("shown %d notifications, want the first and one after the snooze", len(shown))
+	}
+}
+
+func TestAwaitSnoozesFromAPI(t *testing.T) {
+	notifier := &fakeNotifier{actions: true, answers: []string{unanswered, notify.StartNext}}
+	server := api.NewServer(nil, nil, 0, nil)
+	web := httptest.NewServer(server.Handler())
+	defer web.Close()
+
+	snoozed := make(chan int, 1)
+	go func() {
+		// keep asking until the alarm is rung
+		for {
+			res, err := http.Post(web.URL+"/api/timer/snooze", "application/json", bytes.NewReader(nil))
+			if err != nil {
+				snoozed <- 0
+				return
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusNotFound {
+				snoozed <- res.StatusCode
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	if !await(context.Background(), notifier, sessionEnded(), 5*time.Second, 10*time.Millisecond, server) {
+		t.Error("start next after a snooze did not start the next session")
+	}
+	if status := <-snoozed; status != http.StatusNoContent {
+		t.Errorf("snoozing through the api answered %d", status)
+	}
+	if shown := notifier.notifications(); len(shown) != 2 {
+		t.Errorf("shown %d notifications, want the first and one after the snooze", len(shown))
+	}
+}
+
+func TestAwaitStopsWhenCancelled(t *testing.T) {
+	notifier := &fakeNotifier{actions: true, answers: []string{notify.Snooze}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan bool)
+	go func() {
+		done <- await(ctx, notifier, sessionEnded(), time.Minute, time.Hour, nil)
+	}()
+	cancel()
+
+	select {
+	case again := <-done:
+		if again {
+			t.Error("cancelling during a snooze started the next session")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelling did not end the snooze")
+	}
+}
+
+func TestAwaitWithoutActionsOnlyShows(t *testing.T) {
+	notifier := &fakeNotifier{}
+
+	start := time.Now()
+	if await(context.Background(), notifier, sessionEnded(), time.Hour, time.Minute, nil) {
+		t.Error("a notifier without actions started the next session")
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Errorf("waited %s for a notifier that cannot be answered", waited)
+	}
+
+	shown := notifier.notifications()
+	if len(shown) != 1 || len(shown[0].Actions) != 0 {
+		t.Errorf("shown %+v, want one notification without actions", shown)
+	}
+}
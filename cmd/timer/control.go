@@ -0,0 +1,133 @@
+package timer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aelnahas/pomo/api"
+	"github.com/aelnahas/pomo/cmd/config"
+	"github.com/spf13/cobra"
+)
+
+type controlOptions struct {
+	api string
+}
+
+// NewRemoteCmd is the timer command for when another pomo, a running timer
+// or pomo serve, holds the databases: it only has the commands that go
+// through its api, starting a timer in pomo serve and driving a running one.
+func NewRemoteCmd(version string, config *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "timer <command> [flags]",
+		Aliases: []string{"t"},
+		Short:   "control the running pomo timer",
+		Version: version,
+	}
+
+	cmd.AddCommand(newRemoteStartCmd(version, config))
+	cmd.AddCommand(controlCmds(version, config, true)...)
+	return cmd
+}
+
+// newRemoteStartCmd starts the timer in pomo serve, which runs it headless.
+func newRemoteStartCmd(version string, config *config.Config) *cobra.Command {
+	opts := controlOptions{}
+	cmd := &cobra.Command{
+		Use:     "start",
+		Short:   "start a timer in pomo serve",
+		Long:    "start a timer for the current session in the pomo serve that holds the databases, then drive it with the other timer commands or the dashboard",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state, err := api.NewClient(opts.api).Start()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("started %s, %s left\n", describe(state.Type), state.Remaining.Round(time.Second))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.api, "api", apiAddress(config), "address pomo serve listens on")
+	return cmd
+}
+
+func apiAddress(config *config.Config) string {
+	if config.API.Listen != "" {
+		return config.API.Listen
+	}
+	return api.DefaultListen
+}
+
+// errNoTimer is what the control commands answer in a pomo that holds the
+// databases itself, which means no timer or pomo serve is running.
+var errNoTimer = errors.New("no timer is running, start one with timer start --listen or in pomo serve")
+
+// controlCmds extend, skip and snooze a timer started with --listen or in
+// pomo serve. When running is false this process holds the databases, so
+// there is no timer to talk to and they only say so.
+func controlCmds(version string, config *config.Config, running bool) []*cobra.Command {
+	opts := controlOptions{}
+
+	extend := &cobra.Command{
+		Use:     "extend [minutes]",
+		Short:   "add time to the running session",
+		Long:    "add time to the session running in a timer started with --listen, timers.extend unless given in minutes",
+		Example: "timer extend 10",
+		Args:    cobra.MaximumNArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			minutes := int(config.Timers.ExtendDuration() / time.Minute)
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("minutes must be a number (%w)", err)
+				}
+				minutes = n
+			}
+			if minutes < 1 {
+				minutes = 1
+			}
+
+			state, err := api.NewClient(opts.api).Extend(minutes)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("added %dm, %s left\n", minutes, state.Remaining.Round(time.Second))
+			return nil
+		},
+	}
+
+	skip := &cobra.Command{
+		Use:     "skip",
+		Short:   "skip the current break",
+		Long:    "end the break running in a timer started with --listen",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return api.NewClient(opts.api).Skip()
+		},
+	}
+
+	snooze := &cobra.Command{
+		Use:     "snooze",
+		Short:   "snooze a finished session's alarm",
+		Long:    "put off the notification of a session that just finished in a timer started with --listen, for notifications.snooze",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return api.NewClient(opts.api).Snooze()
+		},
+	}
+
+	listen := apiAddress(config)
+	cmds := []*cobra.Command{extend, skip, snooze}
+	for _, c := range cmds {
+		c.Flags().StringVar(&opts.api, "api", listen, "address the timer's api listens on")
+		if !running {
+			c.RunE = func(cmd *cobra.Command, args []string) error {
+				return errNoTimer
+			}
+		}
+	}
+	return cmds
+}
@@ -0,0 +1,171 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aelnahas/pomo/api"
+	"github.com/aelnahas/pomo/cmd/config"
+	"github.com/aelnahas/pomo/countdown"
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/notify"
+	"github.com/aelnahas/pomo/output"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+// Launcher runs the timers pomo serve starts through its api. They run
+// headless, on a screen nobody sees, are driven through the api and
+// announce the next session when they end.
+type Launcher struct {
+	ctx          context.Context
+	config       *config.Config
+	store        task.Store
+	sessionStore sessions.Store
+	bus          *events.Bus
+	server       *api.Server
+	notifier     notify.Notifier
+
+	// mu guards stop, which is closed to void the running timer and nil
+	// when no timer is running, and stopped, which is set once Stop has
+	// been called so no more timers start.
+	mu      sync.Mutex
+	stop    chan struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+func NewLauncher(ctx context.Context, config *config.Config, store task.Store, sessionStore sessions.Store, bus *events.Bus, server *api.Server) (*Launcher, error) {
+	notifier, err := config.Notifications.Notifier()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Launcher{
+		ctx:          ctx,
+		config:       config,
+		store:        store,
+		sessionStore: sessionStore,
+		bus:          bus,
+		server:       server,
+		notifier:     notifier,
+	}, nil
+}
+
+// Launch starts a timer for the current session and returns once it is
+// running.
+func (l *Launcher) Launch() error {
+	l.mu.Lock()
+	switch {
+	case l.stopped:
+		l.mu.Unlock()
+		return errors.New("the server is shutting down")
+	case l.stop != nil:
+		l.mu.Unlock()
+		return errors.New("a timer is already running")
+	}
+	stop := make(chan struct{})
+	l.stop = stop
+	l.mu.Unlock()
+
+	updates, unsubscribe := l.bus.Subscribe(16)
+	defer unsubscribe()
+
+	failed := make(chan error, 1)
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		err := l.run(headless{Buffer: countdown.NewBuffer(80, 24), stop: stop})
+
+		l.mu.Lock()
+		if l.stop == stop {
+			l.stop = nil
+		}
+		l.mu.Unlock()
+
+		if err != nil && !errors.Is(err, errStopped) {
+			fmt.Fprintf(os.Stderr, "timer failed (%s)\n", err)
+		}
+		failed <- err
+	}()
+
+	for {
+		select {
+		case e := <-updates:
+			if e.Type == events.Started {
+				return nil
+			}
+		case err := <-failed:
+			return err
+		}
+	}
+}
+
+func (l *Launcher) run(screen countdown.Renderer) error {
+	current, finished, err := runSession(l.config, l.store, l.sessionStore, l.bus, l.server, screen, false)
+	if err != nil {
+		return err
+	}
+	output.Printlist(*current)
+
+	next, err := l.sessionStore.Current()
+	if err != nil {
+		return err
+	}
+
+	_, snooze, err := l.config.Notifications.Durations()
+	if err != nil {
+		return err
+	}
+	_, err = advance(l.ctx, l.notifier, ended(current, finished, next, snooze), false, screen, snooze)
+	return err
+}
+
+// Stop voids the running timer, if there is one, and waits for it.
+func (l *Launcher) Stop() {
+	l.mu.Lock()
+	l.stopped = true
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	if c, ok := l.notifier.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// headless is a screen for a timer nobody watches. Nothing is typed on
+// it until stop is closed, which quits and voids the session.
+type headless struct {
+	*countdown.Buffer
+	stop <-chan struct{}
+}
+
+func (h headless) Keys(done <-chan struct{}) <-chan countdown.Key {
+	keys := make(chan countdown.Key)
+	go func() {
+		defer close(keys)
+		select {
+		case <-h.stop:
+		case <-done:
+			return
+		}
+
+		// quitting asks what to do with the session, v voids it
+		for _, k := range []countdown.Key{countdown.KeyCtrlC, 'v'} {
+			select {
+			case keys <- k:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return keys
+}
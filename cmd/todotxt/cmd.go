@@ -0,0 +1,130 @@
+package todotxt
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aelnahas/pomo/cmd/config"
+	"github.com/aelnahas/pomo/task"
+	"github.com/aelnahas/pomo/todotxt"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	all  bool
+	path string
+}
+
+func NewCmd(version string, c *config.Config, store task.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "todotxt <command> [flags]",
+		Aliases: []string{"todo"},
+		Short:   "import, export and sync todo.txt files",
+		Version: version,
+	}
+
+	cmd.AddCommand(newImportCmd(version, store))
+	cmd.AddCommand(newExportCmd(version, store))
+	cmd.AddCommand(newSyncCmd(version, c, store))
+	return cmd
+}
+
+func newImportCmd(version string, store task.Store) *cobra.Command {
+	return &cobra.Command{
+		Use:     "import <file>",
+		Short:   "import tasks from a todo.txt file",
+		Example: "todotxt import ~/todo.txt",
+		Args:    cobra.ExactArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			tasks, err := todotxt.Read(f)
+			if err != nil {
+				return err
+			}
+
+			summary, err := todotxt.Import(store, tasks...)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("added %d, updated %d, unchanged %d\n", summary.Added, summary.Updated, summary.Unchanged)
+			return nil
+		},
+	}
+}
+
+func newExportCmd(version string, store task.Store) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "export [file] [flags]",
+		Short:   "export tasks in todo.txt format",
+		Example: "todotxt export ~/todo.txt --all",
+		Args:    cobra.MaximumNArgs(1),
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, err := store.List(func(t task.Task) bool {
+				return opts.all || t.Status != task.Complete
+			})
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			if len(args) == 1 {
+				f, err := os.Create(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			return todotxt.Write(w, tasks...)
+		},
+	}
+
+	cmd.PersistentFlags().BoolVarP(&opts.all, "all", "a", false, "include completed tasks")
+	return cmd
+}
+
+func newSyncCmd(version string, c *config.Config, store task.Store) *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:     "sync [flags]",
+		Short:   "two-way sync with the configured todo.txt file",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := opts.path
+			if path == "" {
+				path = c.TodoTxt.Path
+			}
+			if path == "" {
+				return fmt.Errorf("no todo.txt path configured, set todotxt.path or pass --path")
+			}
+
+			path, err := config.ExpandPath(path)
+			if err != nil {
+				return err
+			}
+
+			summary, err := todotxt.Sync(store, path)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("added %d, updated %d, unchanged %d, removed %d, wrote %d tasks to %s\n",
+				summary.Added, summary.Updated, summary.Unchanged, summary.Removed, summary.Written, path)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.path, "path", "p", "", "todo.txt file to sync with, defaults to todotxt.path")
+	return cmd
+}
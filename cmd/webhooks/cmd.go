@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aelnahas/pomo/output"
+	"github.com/aelnahas/pomo/webhooks"
+	"github.com/spf13/cobra"
+)
+
+func NewCmd(version string, sender *webhooks.Sender) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "webhooks <command>",
+		Aliases: []string{"wh"},
+		Short:   "inspect and deliver queued webhooks",
+		Long:    "list the deliveries waiting in the outbox, or retry them now instead of waiting for their backoff. Deliveries go out in the background while timer start or serve runs, other commands only queue them",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deliveries, unreadable, err := sender.Outbox().List()
+			if err != nil {
+				return err
+			}
+
+			output.PrintDeliveries(deliveries...)
+			printUnreadable(sender, unreadable)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(newFlushCmd(version, sender))
+	return cmd
+}
+
+func newFlushCmd(version string, sender *webhooks.Sender) *cobra.Command {
+	return &cobra.Command{
+		Use:     "flush",
+		Short:   "retry every queued delivery now",
+		Version: version,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := sender.Flush(true)
+			printUnreadable(sender, result.Unreadable)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("sent %d, pending %d, failed %d\n", result.Sent, result.Pending, result.Failed)
+			return nil
+		},
+	}
+}
+
+func printUnreadable(sender *webhooks.Sender, names []string) {
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "%s is not a delivery, moved to %s\n", name, sender.Outbox().FailedDir())
+	}
+}
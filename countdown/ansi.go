@@ -0,0 +1,169 @@
+package countdown
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// ANSI draws frames as plain lines of text with ANSI colors, for terminals
+// termbox cannot drive. When in is a terminal it is put in raw mode and
+// read for keys.
+type ANSI struct {
+	*Buffer
+	out     io.Writer
+	in      *os.File
+	restore func()
+	resized bool
+}
+
+func NewANSI(out io.Writer, in *os.File) *ANSI {
+	return &ANSI{Buffer: NewBuffer(defaultWidth, defaultHeight), out: out, in: in}
+}
+
+func (a *ANSI) Init() error {
+	a.Resize(a.size())
+
+	if a.in != nil && term.IsTerminal(int(a.in.Fd())) {
+		state, err := term.MakeRaw(int(a.in.Fd()))
+		if err != nil {
+			return err
+		}
+		a.restore = func() {
+			term.Restore(int(a.in.Fd()), state)
+		}
+	}
+
+	_, err := fmt.Fprint(a.out, "\x1b[?25l\x1b[2J")
+	return err
+}
+
+func (a *ANSI) Close() error {
+	if a.restore != nil {
+		a.restore()
+	}
+	_, err := fmt.Fprint(a.out, "\x1b[0m\x1b[H\x1b[2J\x1b[?25h")
+	return err
+}
+
+// Size follows the terminal, picking up resizes on the next frame.
+func (a *ANSI) Size() (int, int) {
+	if w, h := a.size(); w != a.width || h != a.height {
+		a.Resize(w, h)
+		a.resized = true
+	}
+	return a.width, a.height
+}
+
+func (a *ANSI) size() (int, int) {
+	if f, ok := a.out.(*os.File); ok {
+		if w, h, err := term.GetSize(int(f.Fd())); err == nil && w > 0 && h > 0 {
+			return w, h
+		}
+	}
+	return defaultWidth, defaultHeight
+}
+
+func (a *ANSI) Flush() error {
+	if err := a.Buffer.Flush(); err != nil {
+		return err
+	}
+
+	var frame strings.Builder
+	if a.resized {
+		frame.WriteString("\x1b[2J")
+		a.resized = false
+	}
+	frame.WriteString("\x1b[H")
+	for y := 0; y < a.height; y++ {
+		current := ColorDefault
+		for x := 0; x < a.width; x++ {
+			c := a.Cell(x, y)
+			if c.Color != current {
+				frame.WriteString(sgr(c.Color))
+				current = c.Color
+			}
+			frame.WriteRune(c.Rune)
+		}
+		frame.WriteString("\x1b[0m")
+		if y < a.height-1 {
+			frame.WriteString("\r\n")
+		}
+	}
+
+	_, err := io.WriteString(a.out, frame.String())
+	return err
+}
+
+func sgr(c Color) string {
+	v, ok := rgb[c]
+	if !ok {
+		return "\x1b[39m"
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", v[0], v[1], v[2])
+}
+
+var (
+	stdinOnce sync.Once
+	stdin     chan Key
+)
+
+// Keys reads keys from in. One reader serves every countdown in the
+// process, so a key pressed between sessions is not swallowed by a reader
+// left over from the last one.
+func (a *ANSI) Keys(done <-chan struct{}) <-chan Key {
+	if a.restore == nil {
+		return nil
+	}
+
+	stdinOnce.Do(func() {
+		stdin = make(chan Key)
+		go func() {
+			r := bufio.NewReader(a.in)
+			for {
+				c, _, err := r.ReadRune()
+				if err != nil {
+					close(stdin)
+					return
+				}
+				if c == '\b' {
+					c = rune(KeyBackspace)
+				}
+				stdin <- Key(c)
+			}
+		}()
+	})
+
+	keys := make(chan Key)
+	go func() {
+		for {
+			var key Key
+			select {
+			case <-done:
+				return
+			case k, ok := <-stdin:
+				if !ok {
+					return
+				}
+				key = k
+			}
+
+			select {
+			case keys <- key:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return keys
+}
@@ -0,0 +1,81 @@
+package countdown
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Ask puts question in the middle of screen and waits for one of answers.
+// It returns 0 when wait, if not zero, runs out first. Screens that cannot
+// read keys get the first answer straight away.
+func Ask(screen Renderer, question []string, wait time.Duration, answers ...Key) (Key, error) {
+	input, ok := screen.(Input)
+	if !ok || len(answers) == 0 {
+		if len(answers) == 0 {
+			return 0, nil
+		}
+		return answers[0], nil
+	}
+
+	if err := screen.Init(); err != nil {
+		return 0, err
+	}
+	defer screen.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	keys := input.Keys(done)
+	if keys == nil {
+		return answers[0], nil
+	}
+
+	var resized <-chan struct{}
+	if r, ok := screen.(Resizer); ok {
+		resized = r.Resized()
+	}
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		drawQuestion(screen, question)
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return answers[0], nil
+			}
+			for _, a := range answers {
+				if k == a {
+					return k, nil
+				}
+			}
+		case <-resized:
+		case <-timeout:
+			return 0, nil
+		}
+	}
+}
+
+func drawQuestion(screen Renderer, question []string) {
+	w, h := screen.Size()
+	screen.Clear()
+	defer screen.Flush()
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	y := max(0, h/2-len(question)/2)
+	for _, line := range question {
+		if y >= h {
+			break
+		}
+		line = clip(line, w)
+		echo(screen, Symbol{line}, max(0, w/2-utf8.RuneCountInString(line)/2), y, ColorDefault)
+		y++
+	}
+}
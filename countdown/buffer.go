@@ -0,0 +1,95 @@
+package countdown
+
+import "strings"
+
+type Cell struct {
+	Rune  rune
+	Color Color
+}
+
+// Buffer is an in-memory screen. It keeps the last flushed frame, so a
+// frame can be compared against a snapshot of the expected layout.
+type Buffer struct {
+	width, height int
+	cells         [][]Cell
+	frame         [][]Cell
+	Frames        int
+}
+
+func NewBuffer(width, height int) *Buffer {
+	b := &Buffer{}
+	b.Resize(width, height)
+	return b
+}
+
+// Resize changes the screen size and clears it.
+func (b *Buffer) Resize(width, height int) {
+	b.width, b.height = width, height
+	b.cells = blank(width, height)
+	b.frame = blank(width, height)
+}
+
+func blank(width, height int) [][]Cell {
+	cells := make([][]Cell, height)
+	for y := range cells {
+		cells[y] = make([]Cell, width)
+		for x := range cells[y] {
+			cells[y][x] = Cell{Rune: ' '}
+		}
+	}
+	return cells
+}
+
+func (b *Buffer) Init() error {
+	return nil
+}
+
+func (b *Buffer) Close() error {
+	return nil
+}
+
+func (b *Buffer) Size() (int, int) {
+	return b.width, b.height
+}
+
+func (b *Buffer) Clear() {
+	b.cells = blank(b.width, b.height)
+}
+
+func (b *Buffer) SetCell(x, y int, r rune, fg Color) {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return
+	}
+	b.cells[y][x] = Cell{Rune: r, Color: fg}
+}
+
+func (b *Buffer) Flush() error {
+	b.frame = make([][]Cell, len(b.cells))
+	for y, row := range b.cells {
+		b.frame[y] = append([]Cell(nil), row...)
+	}
+	b.Frames++
+	return nil
+}
+
+// Cell returns a cell of the last flushed frame.
+func (b *Buffer) Cell(x, y int) Cell {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return Cell{}
+	}
+	return b.frame[y][x]
+}
+
+// String returns the last flushed frame as text, one line per row with
+// trailing spaces trimmed.
+func (b *Buffer) String() string {
+	lines := make([]string, len(b.frame))
+	for y, row := range b.frame {
+		var line strings.Builder
+		for _, c := range row {
+			line.WriteRune(c.Rune)
+		}
+		lines[y] = strings.TrimRight(line.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,399 @@
+package countdown
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aelnahas/pomo/clock"
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+var (
+	// ErrInterrupted is returned by Run when the session is voided, it
+	// should leave no trace.
+	ErrInterrupted = errors.New("Error: timer interrupted")
+
+	// ErrAbandoned is returned by Run when the session is given up part
+	// way, either on purpose or, wrapped, when the timer stays paused longer
+	// than the limit set with AbandonAfter.
+	ErrAbandoned = errors.New("timer abandoned")
+
+	// ErrSkipped is returned by Run when a break is skipped.
+	ErrSkipped = errors.New("break skipped")
+
+	// ErrNotRunning is returned when asking a timer that is not running to
+	// change.
+	ErrNotRunning = errors.New("timer is not running")
+)
+
+// Command is an input to a running engine.
+type Command int
+
+const (
+	Pause Command = iota
+	Resume
+	Interrupt
+	// Finish completes the session, early unless it counts up.
+	Finish
+	Abandon
+)
+
+const tick = time.Second
+
+// Engine is the timer's state machine. It knows nothing about screens: it
+// takes commands, keeps time with a clock.Clock and reports everything that
+// happens as events on the bus.
+type Engine struct {
+	// mu guards the state below, which State reads from other goroutines.
+	mu       sync.Mutex
+	elapsed  time.Duration
+	resumed  time.Time
+	paused   bool
+	pauses   []sessions.Pause
+	maxPause time.Duration
+
+	interruptions []sessions.Interruption
+	extensions    []sessions.Extension
+
+	// requests carries the changes asked for with Extend and Skip to Run,
+	// which closes stopped when it returns.
+	requests chan request
+	stopped  chan struct{}
+
+	clock        clock.Clock
+	timer        clock.Timer
+	ticker       clock.Timer
+	countUp      bool
+	duration     time.Duration
+	task         *task.Task
+	sessiontType sessions.Type
+	bus          *events.Bus
+}
+
+func (e *Engine) counting() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.countUp
+}
+
+// CountUp makes the engine count up from zero with no fixed end, running
+// until it gets a Finish command. Call it before Run.
+func (e *Engine) CountUp() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.countUp = true
+}
+
+func NewEngine(d time.Duration, task *task.Task, sessionType sessions.Type, bus *events.Bus, c clock.Clock) *Engine {
+	if bus == nil {
+		bus = events.NewBus()
+	}
+	if c == nil {
+		c = clock.Real{}
+	}
+
+	return &Engine{
+		requests:     make(chan request),
+		stopped:      make(chan struct{}),
+		clock:        c,
+		duration:     d,
+		task:         task,
+		sessiontType: sessionType,
+		bus:          bus,
+	}
+}
+
+// AbandonAfter makes Run give up with ErrAbandoned once the timer has
+// been paused for longer than d. Zero, the default, waits forever.
+func (e *Engine) AbandonAfter(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxPause = d
+}
+
+func (e *Engine) State() events.TimerState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state := events.TimerState{
+		Type:      e.sessiontType,
+		Task:      e.task,
+		Duration:  e.duration,
+		Remaining: e.duration - e.active(),
+		Elapsed:   e.active(),
+		Paused:    e.paused,
+	}
+	if e.countUp {
+		state.Duration, state.Remaining = 0, 0
+	}
+	return state
+}
+
+// Elapsed is the time the timer has been running, not counting pauses.
+func (e *Engine) Elapsed() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active()
+}
+
+// Pauses returns the pauses taken so far, for the session record.
+func (e *Engine) Pauses() []sessions.Pause {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sessions.Pause(nil), e.pauses...)
+}
+
+// Log records an interruption that did not end the session.
+func (e *Engine) Log(i sessions.Interruption) {
+	e.mu.Lock()
+	e.interruptions = append(e.interruptions, i)
+	e.mu.Unlock()
+
+	e.bus.Publish(events.Event{
+		Type:         events.Distracted,
+		Session:      e.sessiontType,
+		Task:         e.task,
+		Interruption: &i,
+	})
+}
+
+// Interruptions returns the interruptions logged so far, for the session
+// record.
+func (e *Engine) Interruptions() []sessions.Interruption {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sessions.Interruption(nil), e.interruptions...)
+}
+
+// Extensions returns the extensions made so far, for the session record.
+func (e *Engine) Extensions() []sessions.Extension {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sessions.Extension(nil), e.extensions...)
+}
+
+// request is a change asked of a running engine, answered on err.
+type request struct {
+	extend time.Duration
+	skip   bool
+	err    chan error
+}
+
+// Extend adds d to the session while Run is going. Sessions that count up
+// have no end to move.
+func (e *Engine) Extend(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("cannot extend a session by %s", d)
+	}
+	return e.ask(request{extend: d})
+}
+
+// Skip ends a break early while Run is going, Run returns ErrSkipped.
+func (e *Engine) Skip() error {
+	return e.ask(request{skip: true})
+}
+
+func (e *Engine) ask(r request) error {
+	r.err = make(chan error, 1)
+	select {
+	case e.requests <- r:
+		return <-r.err
+	case <-e.stopped:
+		return ErrNotRunning
+	}
+}
+
+// extend moves the end of the session, restarting the clock's timer
+// unless it is paused.
+func (e *Engine) extend(d time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.countUp {
+		return errors.New("a flow session has no end to extend")
+	}
+
+	e.duration += d
+	e.extensions = append(e.extensions, sessions.Extension{At: e.clock.Now(), By: d})
+	if !e.paused {
+		clock.Drain(e.timer)
+		e.timer = e.clock.NewTimer(e.duration - e.active())
+	}
+	return nil
+}
+
+// active is the time spent running. The real clock's times carry monotonic
+// readings, so wall clock jumps do not skew it. The caller must hold mu.
+func (e *Engine) active() time.Duration {
+	if e.paused || e.resumed.IsZero() {
+		return e.elapsed
+	}
+	return e.elapsed + e.clock.Since(e.resumed)
+}
+
+func (e *Engine) publish(eventType events.Type) {
+	state := e.State()
+	if eventType == events.Completed {
+		state.Remaining = 0
+	}
+
+	e.bus.Publish(events.Event{
+		Type:      eventType,
+		Session:   e.sessiontType,
+		Task:      e.task,
+		Duration:  state.Duration,
+		Remaining: state.Remaining,
+		Elapsed:   state.Elapsed,
+	})
+}
+
+// run starts the clock with whatever time is left. A clock counting up has
+// no end to wait for, only ticks.
+func (e *Engine) run() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.resumed = e.clock.Now()
+	e.paused = false
+	if !e.countUp {
+		e.timer = e.clock.NewTimer(e.duration - e.elapsed)
+	}
+	e.ticker = e.clock.NewTimer(e.nextTick())
+}
+
+// done fires when the time is up, never when counting up.
+func (e *Engine) done() <-chan time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.timer == nil {
+		return nil
+	}
+	return e.timer.C()
+}
+
+// pause stops the clock, banking the time run so far.
+func (e *Engine) pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.clock.Now()
+	e.elapsed += now.Sub(e.resumed)
+	e.paused = true
+	e.pauses = append(e.pauses, sessions.Pause{Start: now})
+	clock.Drain(e.timer)
+	clock.Drain(e.ticker)
+}
+
+// resume closes the current pause and restarts the clock.
+func (e *Engine) resume() {
+	e.mu.Lock()
+	e.pauses[len(e.pauses)-1].End = e.clock.Now()
+	e.mu.Unlock()
+	e.run()
+}
+
+func (e *Engine) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.paused && len(e.pauses) > 0 {
+		e.pauses[len(e.pauses)-1].End = e.clock.Now()
+	}
+	clock.Drain(e.timer)
+	clock.Drain(e.ticker)
+}
+
+// nextTick schedules ticks on whole seconds of running time, so they stay
+// in step with the clock however often it is paused. The caller must hold
+// mu.
+func (e *Engine) nextTick() time.Duration {
+	return tick - e.active()%tick
+}
+
+func (e *Engine) abandonAfter() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.maxPause
+}
+
+// Run counts down until the session completes, is interrupted or is
+// abandoned, applying commands as they arrive. Counting up, the session
+// only completes when it is finished.
+func (e *Engine) Run(commands <-chan Command) error {
+	e.run()
+	defer e.stop()
+	defer close(e.stopped)
+	e.publish(events.Started)
+
+	var abandon clock.Timer
+	abandoned := func() <-chan time.Time {
+		if abandon == nil {
+			return nil
+		}
+		return abandon.C()
+	}
+	defer func() {
+		clock.Drain(abandon)
+	}()
+
+	for {
+		select {
+		case command := <-commands:
+			switch {
+			case command == Interrupt:
+				e.publish(events.Interrupted)
+				return ErrInterrupted
+			case command == Finish:
+				e.publish(events.Completed)
+				return nil
+			case command == Abandon:
+				e.publish(events.Interrupted)
+				return ErrAbandoned
+			case command == Pause && !e.State().Paused:
+				e.pause()
+				if max := e.abandonAfter(); max > 0 {
+					abandon = e.clock.NewTimer(max)
+				}
+				e.publish(events.Paused)
+			case command == Resume && e.State().Paused:
+				clock.Drain(abandon)
+				abandon = nil
+				e.resume()
+				e.publish(events.Resumed)
+			}
+		case r := <-e.requests:
+			if !r.skip {
+				err := e.extend(r.extend)
+				r.err <- err
+				if err == nil {
+					e.publish(events.Extended)
+				}
+				continue
+			}
+
+			if e.sessiontType == sessions.Focus {
+				r.err <- errors.New("only breaks can be skipped")
+				continue
+			}
+			r.err <- nil
+			e.publish(events.Skipped)
+			return ErrSkipped
+		case <-e.ticker.C():
+			e.mu.Lock()
+			e.ticker.Reset(e.nextTick())
+			e.mu.Unlock()
+			e.publish(events.Tick)
+		case <-e.done():
+			e.publish(events.Completed)
+			return nil
+		case <-abandoned():
+			e.publish(events.Interrupted)
+			return fmt.Errorf("%w after pausing for %s", ErrAbandoned, e.abandonAfter())
+		}
+	}
+}
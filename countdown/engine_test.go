@@ -0,0 +1,388 @@
+package countdown
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aelnahas/pomo/clock"
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+var epoch = time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// run is an engine running on a fake clock, with its events and the
+// channel Run takes commands on.
+type run struct {
+	t        *testing.T
+	engine   *Engine
+	clock    *clock.Fake
+	events   <-chan events.Event
+	commands chan Command
+	done     chan error
+}
+
+// configure adjusts an engine before it runs.
+type configure func(e *Engine)
+
+func countingUp(e *Engine) {
+	e.CountUp()
+}
+
+func abandoningAfter(d time.Duration) configure {
+	return func(e *Engine) {
+		e.AbandonAfter(d)
+	}
+}
+
+// start runs an engine for a session of d and waits for it to start.
+func start(t *testing.T, d time.Duration, sessionType sessions.Type, options ...configure) *run {
+	t.Helper()
+
+	bus := events.NewBus()
+	updates, unsubscribe := bus.Subscribe(64)
+	t.Cleanup(unsubscribe)
+
+	fake := clock.NewFake(epoch)
+	e := NewEngine(d, &task.Task{Title: "write tests"}, sessionType, bus, fake)
+	for _, option := range options {
+		option(e)
+	}
+
+	r := &run{
+		t:        t,
+		engine:   e,
+		clock:    fake,
+		events:   updates,
+		commands: make(chan Command),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		r.done <- e.Run(r.commands)
+	}()
+
+	started := r.next()
+	if started.Type != events.Started {
+		t.Fatalf("first event is %s, want %s", started.Type, events.Started)
+	}
+	if started.Session != sessionType || started.Task.Title != "write tests" {
+		t.Errorf("started %s for %v", started.Session, started.Task)
+	}
+	return r
+}
+
+func (r *run) next() events.Event {
+	r.t.Helper()
+	select {
+	case e := <-r.events:
+		return e
+	case <-time.After(5 * time.Second):
+		r.t.Fatal("timed out waiting for an event")
+	}
+	return events.Event{}
+}
+
+// expect waits for the next event other than a tick and checks its type.
+func (r *run) expect(eventType events.Type) events.Event {
+	r.t.Helper()
+	for {
+		e := r.next()
+		if e.Type == events.Tick && eventType != events.Tick {
+			continue
+		}
+		if e.Type != eventType {
+			r.t.Fatalf("got %s, want %s", e.Type, eventType)
+		}
+		return e
+	}
+}
+
+// tick moves the clock on a second and waits for the engine to tick.
+func (r *run) tick() events.Event {
+	r.t.Helper()
+	r.clock.Advance(time.Second)
+	return r.expect(events.Tick)
+}
+
+func (r *run) send(command Command) {
+	r.t.Helper()
+	select {
+	case r.commands <- command:
+	case <-time.After(5 * time.Second):
+		r.t.Fatal("the engine is not taking commands")
+	}
+}
+
+// result waits for Run to return.
+func (r *run) result() error {
+	r.t.Helper()
+	select {
+	case err := <-r.done:
+		return err
+	case <-time.After(5 * time.Second):
+		r.t.Fatal("timed out waiting for the engine to stop")
+	}
+	return nil
+}
+
+func TestEngineCompletes(t *testing.T) {
+	r := start(t, 3*time.Second, sessions.Focus)
+
+	for _, remaining := range []time.Duration{2 * time.Second, time.Second} {
+		e := r.tick()
+		if e.Remaining != remaining || e.Duration != 3*time.Second {
+			t.Errorf("tick left %s of %s, want %s of 3s", e.Remaining, e.Duration, remaining)
+		}
+	}
+
+	r.clock.Advance(time.Second)
+	e := r.expect(events.Completed)
+	if e.Remaining != 0 || e.Elapsed != 3*time.Second {
+		t.Errorf("completed with %s left after %s, want none after 3s", e.Remaining, e.Elapsed)
+	}
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+	if len(r.engine.Pauses()) != 0 {
+		t.Errorf("recorded pauses %v without pausing", r.engine.Pauses())
+	}
+}
+
+func TestEnginePausesAndResumes(t *testing.T) {
+	r := start(t, 3*time.Second, sessions.Focus)
+	r.tick()
+
+	r.send(Pause)
+	r.expect(events.Paused)
+	r.clock.Advance(10 * time.Second)
+	if state := r.engine.State(); !state.Paused || state.Remaining != 2*time.Second {
+		t.Errorf("paused state %+v, want 2s left", state)
+	}
+
+	// pausing twice changes nothing
+	r.send(Pause)
+	r.send(Resume)
+	r.expect(events.Resumed)
+
+	if e := r.tick(); e.Remaining != time.Second {
+		t.Errorf("after resuming a tick left %s, want 1s", e.Remaining)
+	}
+	r.clock.Advance(time.Second)
+	if e := r.expect(events.Completed); e.Elapsed != 3*time.Second {
+		t.Errorf("completed after %s of running, want 3s", e.Elapsed)
+	}
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+
+	pauses := r.engine.Pauses()
+	if len(pauses) != 1 {
+		t.Fatalf("recorded %d pauses, want 1", len(pauses))
+	}
+	if got := pauses[0].End.Sub(pauses[0].Start); got != 10*time.Second {
+		t.Errorf("pause lasted %s, want 10s", got)
+	}
+}
+
+func TestEngineAbandonsAfterLongPause(t *testing.T) {
+	r := start(t, time.Minute, sessions.Focus, abandoningAfter(5*time.Second))
+	r.tick()
+
+	r.send(Pause)
+	r.expect(events.Paused)
+	r.clock.Advance(4 * time.Second)
+	r.send(Resume)
+	r.expect(events.Resumed)
+
+	// the limit starts over with every pause
+	r.send(Pause)
+	r.expect(events.Paused)
+	r.clock.Advance(4 * time.Second)
+	if state := r.engine.State(); !state.Paused {
+		t.Fatal("abandoned before the limit")
+	}
+
+	r.clock.Advance(time.Second)
+	r.expect(events.Interrupted)
+	err := r.result()
+	if !errors.Is(err, ErrAbandoned) || err == ErrAbandoned {
+		t.Errorf("Run returned %v, want a wrapped %v", err, ErrAbandoned)
+	}
+
+	pauses := r.engine.Pauses()
+	if len(pauses) != 2 || pauses[1].End.Sub(pauses[1].Start) != 5*time.Second {
+		t.Errorf("recorded pauses %v, want the last closed after 5s", pauses)
+	}
+}
+
+func TestEngineInterrupt(t *testing.T) {
+	r := start(t, time.Minute, sessions.Focus)
+	r.tick()
+
+	r.send(Interrupt)
+	if e := r.expect(events.Interrupted); e.Elapsed != time.Second {
+		t.Errorf("interrupted after %s, want 1s", e.Elapsed)
+	}
+	if err := r.result(); err != ErrInterrupted {
+		t.Errorf("Run returned %v, want %v", err, ErrInterrupted)
+	}
+}
+
+func TestEngineAbandon(t *testing.T) {
+	r := start(t, time.Minute, sessions.Focus)
+
+	r.send(Abandon)
+	r.expect(events.Interrupted)
+	if err := r.result(); err != ErrAbandoned {
+		t.Errorf("Run returned %v, want %v", err, ErrAbandoned)
+	}
+}
+
+func TestEngineFinishesEarly(t *testing.T) {
+	r := start(t, time.Minute, sessions.Focus)
+	r.tick()
+
+	r.send(Finish)
+	r.expect(events.Completed)
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+	if elapsed := r.engine.Elapsed(); elapsed != time.Second {
+		t.Errorf("finished after %s, want 1s", elapsed)
+	}
+}
+
+func TestEngineFinishesCountingUp(t *testing.T) {
+	r := start(t, time.Second, sessions.Focus, countingUp)
+
+	// counting up runs past the duration it was given
+	for i := 1; i <= 3; i++ {
+		e := r.tick()
+		if e.Elapsed != time.Duration(i)*time.Second || e.Duration != 0 || e.Remaining != 0 {
+			t.Errorf("tick %d at %s elapsed with %s of %s left", i, e.Elapsed, e.Remaining, e.Duration)
+		}
+	}
+
+	if err := r.engine.Extend(time.Minute); err == nil {
+		t.Error("extended a session counting up")
+	}
+
+	r.send(Finish)
+	if e := r.expect(events.Completed); e.Elapsed != 3*time.Second {
+		t.Errorf("finished after %s, want 3s", e.Elapsed)
+	}
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+}
+
+func TestEngineExtends(t *testing.T) {
+	r := start(t, 2*time.Second, sessions.Focus)
+	r.tick()
+
+	if err := r.engine.Extend(0); err == nil {
+		t.Error("extended by nothing")
+	}
+	if err := r.engine.Extend(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	e := r.expect(events.Extended)
+	if e.Duration != 4*time.Second || e.Remaining != 3*time.Second {
+		t.Errorf("extended to %s with %s left, want 4s with 3s left", e.Duration, e.Remaining)
+	}
+
+	// the old end passes without completing
+	r.tick()
+	r.tick()
+	r.clock.Advance(time.Second)
+	r.expect(events.Completed)
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+
+	extensions := r.engine.Extensions()
+	if len(extensions) != 1 || extensions[0].By != 2*time.Second || !extensions[0].At.Equal(epoch.Add(time.Second)) {
+		t.Errorf("recorded extensions %+v", extensions)
+	}
+	if err := r.engine.Extend(time.Minute); err != ErrNotRunning {
+		t.Errorf("extending a stopped engine returned %v, want %v", err, ErrNotRunning)
+	}
+}
+
+func TestEngineExtendsWhilePaused(t *testing.T) {
+	r := start(t, 2*time.Second, sessions.Focus)
+
+	r.send(Pause)
+	r.expect(events.Paused)
+	if err := r.engine.Extend(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if e := r.expect(events.Extended); e.Remaining != 3*time.Second {
+		t.Errorf("extended while paused to %s left, want 3s", e.Remaining)
+	}
+	r.clock.Advance(time.Minute)
+	if state := r.engine.State(); !state.Paused || state.Remaining != 3*time.Second {
+		t.Errorf("the paused clock moved: %+v", state)
+	}
+
+	r.send(Resume)
+	r.expect(events.Resumed)
+	r.clock.Advance(3 * time.Second)
+	r.expect(events.Completed)
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+}
+
+func TestEngineSkipsBreaks(t *testing.T) {
+	r := start(t, time.Minute, sessions.Short)
+	r.tick()
+
+	if err := r.engine.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(events.Skipped)
+	if err := r.result(); err != ErrSkipped {
+		t.Errorf("Run returned %v, want %v", err, ErrSkipped)
+	}
+	if err := r.engine.Skip(); err != ErrNotRunning {
+		t.Errorf("skipping a stopped engine returned %v, want %v", err, ErrNotRunning)
+	}
+}
+
+func TestEngineDoesNotSkipFocus(t *testing.T) {
+	r := start(t, 2*time.Second, sessions.Focus)
+
+	if err := r.engine.Skip(); err == nil {
+		t.Fatal("skipped a focus session")
+	}
+
+	// the session carries on
+	r.tick()
+	r.clock.Advance(time.Second)
+	r.expect(events.Completed)
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+}
+
+func TestEngineLogsInterruptions(t *testing.T) {
+	r := start(t, time.Minute, sessions.Focus)
+
+	r.engine.Log(sessions.Interruption{At: epoch, Kind: sessions.External, Note: "phone"})
+	e := r.expect(events.Distracted)
+	if e.Interruption == nil || e.Interruption.Note != "phone" {
+		t.Errorf("distracted by %+v, want the logged interruption", e.Interruption)
+	}
+
+	r.send(Finish)
+	r.expect(events.Completed)
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+	if got := r.engine.Interruptions(); len(got) != 1 || got[0].Kind != sessions.External {
+		t.Errorf("recorded interruptions %+v", got)
+	}
+}
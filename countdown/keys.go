@@ -0,0 +1,138 @@
+package countdown
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/aelnahas/pomo/sessions"
+)
+
+// maxNote is as long as an interruption note gets, it is a reminder rather
+// than a journal.
+const maxNote = 60
+
+// quitPrompt asks what quitting should do with the session, which carries
+// on while the question is up.
+const quitPrompt = "quit: a abandon  d done early  v void  esc keep going"
+
+// handle turns key presses into engine commands until done is closed. i and
+// e start logging an interruption, the keys after them are its note until
+// enter logs it or esc drops it. Quitting asks whether to abandon the
+// session, complete it early or void it. The prompt being answered, or why
+// an extend or skip was refused, goes to prompts.
+func (c *Countdown) handle(keys <-chan Key, commands chan<- Command, prompts chan<- string, done <-chan struct{}) {
+	send := func(cmd Command) {
+		select {
+		case commands <- cmd:
+		case <-done:
+		}
+	}
+	show := func(line string) {
+		select {
+		case prompts <- line:
+		case <-done:
+		}
+	}
+
+	var (
+		logging  *sessions.Interruption
+		note     []rune
+		quitting bool
+		// failed is set while an error from extending or skipping shows,
+		// the next key takes it down.
+		failed bool
+	)
+	for {
+		var key Key
+		select {
+		case <-done:
+			return
+		case k, ok := <-keys:
+			if !ok {
+				return
+			}
+			key = k
+		}
+
+		if logging != nil {
+			switch key {
+			case KeyCtrlC:
+				send(Interrupt)
+			case KeyEnter:
+				logging.Note = strings.TrimSpace(string(note))
+				c.Log(*logging)
+				logging, note = nil, nil
+				show("")
+			case KeyEsc:
+				logging, note = nil, nil
+				show("")
+			case KeyBackspace:
+				if len(note) > 0 {
+					note = note[:len(note)-1]
+				}
+				show(notePrompt(logging.Kind, note))
+			default:
+				if unicode.IsPrint(rune(key)) && len(note) < maxNote {
+					note = append(note, rune(key))
+				}
+				show(notePrompt(logging.Kind, note))
+			}
+			continue
+		}
+
+		if quitting {
+			switch key {
+			case 'a', 'A':
+				send(Abandon)
+			case 'd', 'D':
+				send(Finish)
+			case 'v', 'V', KeyCtrlC:
+				send(Interrupt)
+			case KeyEsc:
+				quitting = false
+				show("")
+			}
+			continue
+		}
+
+		if failed {
+			failed = false
+			show("")
+		}
+
+		switch key {
+		case KeyEsc, KeyCtrlC, 'q', 'Q':
+			quitting = true
+			show(quitPrompt)
+		case 'p', 'P':
+			send(Pause)
+		case 'c', 'C':
+			send(Resume)
+		case 'f', 'F':
+			if c.counting() {
+				send(Finish)
+			}
+		case '+', '=':
+			if err := c.Extend(c.step); err != nil {
+				failed = true
+				show(err.Error())
+			}
+		case 's', 'S':
+			if err := c.Skip(); err != nil {
+				failed = true
+				show(err.Error())
+			}
+		case 'i', 'I', 'e', 'E':
+			kind := sessions.Internal
+			if key == 'e' || key == 'E' {
+				kind = sessions.External
+			}
+			logging = &sessions.Interruption{At: c.clock.Now(), Kind: kind}
+			show(notePrompt(kind, nil))
+		}
+	}
+}
+
+func notePrompt(kind sessions.InterruptionKind, note []rune) string {
+	return string(kind) + " interruption: " + string(note) + "_  (enter logs, esc drops)"
+}
@@ -0,0 +1,64 @@
+package countdown
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+)
+
+func TestHandleShowsRefusals(t *testing.T) {
+	r := start(t, time.Minute, sessions.Focus, countingUp)
+	c := &Countdown{Engine: r.engine, screen: NewBuffer(80, 24), step: 5 * time.Minute}
+
+	keys := make(chan Key)
+	prompts := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go c.handle(keys, r.commands, prompts, done)
+
+	press := func(k Key) {
+		t.Helper()
+		select {
+		case keys <- k:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q was not read", rune(k))
+		}
+	}
+	prompt := func() string {
+		t.Helper()
+		select {
+		case p := <-prompts:
+			return p
+		case <-time.After(5 * time.Second):
+			t.Fatal("nothing was shown")
+		}
+		return ""
+	}
+
+	press('+')
+	if got := prompt(); got != "a flow session has no end to extend" {
+		t.Errorf("extending a flow session showed %q", got)
+	}
+
+	// the next key takes the error down before doing its own thing
+	press('s')
+	if got := prompt(); got != "" {
+		t.Errorf("the error stayed up as %q", got)
+	}
+	if got := prompt(); got != "only breaks can be skipped" {
+		t.Errorf("skipping focus showed %q", got)
+	}
+
+	press('p')
+	if got := prompt(); got != "" {
+		t.Errorf("the error stayed up as %q", got)
+	}
+	r.expect(events.Paused)
+
+	r.send(Finish)
+	if err := r.result(); err != nil {
+		t.Errorf("Run returned %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package countdown
+
+// Color is a foreground color, mapped to the closest thing each renderer
+// can show.
+type Color int
+
+const (
+	ColorDefault Color = iota
+	Green
+	Red
+	Yellow
+	Cyan
+)
+
+// rgb holds the true colors used by renderers that support them.
+var rgb = map[Color][3]uint8{
+	Green:  {154, 255, 0},
+	Red:    {255, 0, 68},
+	Yellow: {255, 196, 0},
+	Cyan:   {0, 239, 255},
+}
+
+// Renderer is a screen of cells the countdown draws on. Drawing outside
+// Size is ignored, and nothing shows until Flush.
+type Renderer interface {
+	Init() error
+	Close() error
+	Size() (width, height int)
+	Clear()
+	SetCell(x, y int, r rune, fg Color)
+	Flush() error
+}
+
+// Key is a key press: the character typed, or one of the control keys
+// below.
+type Key rune
+
+const (
+	KeyCtrlC     Key = 0x03
+	KeyEnter     Key = '\r'
+	KeyEsc       Key = 0x1b
+	KeyBackspace Key = 0x7f
+)
+
+// Input is implemented by renderers that also read the keyboard. The
+// channel delivers key presses until done is closed.
+type Input interface {
+	Keys(done <-chan struct{}) <-chan Key
+}
+
+// Resizer is implemented by renderers that report when the screen changes
+// size, so the frame can be redrawn without waiting for the next tick.
+type Resizer interface {
+	Resized() <-chan struct{}
+}
+
+func echo(screen Renderer, s Symbol, startX, startY int, fg Color) {
+	x, y := startX, startY
+
+	for _, line := range s {
+		for _, r := range line {
+			screen.SetCell(x, y, r, fg)
+			x++
+		}
+		x = startX
+		y++
+	}
+}
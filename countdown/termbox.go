@@ -0,0 +1,129 @@
+package countdown
+
+import (
+	"sync"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Termbox draws full screen with termbox and reads keys and resizes from
+// it.
+type Termbox struct {
+	resized chan struct{}
+}
+
+func NewTermbox() *Termbox {
+	return &Termbox{resized: make(chan struct{}, 1)}
+}
+
+func (t *Termbox) Init() error {
+	termbox.SetOutputMode(termbox.OutputRGB)
+	return termbox.Init()
+}
+
+func (t *Termbox) Close() error {
+	termbox.Close()
+	return nil
+}
+
+func (t *Termbox) Size() (int, int) {
+	return termbox.Size()
+}
+
+func (t *Termbox) Clear() {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+}
+
+func (t *Termbox) SetCell(x, y int, r rune, fg Color) {
+	termbox.SetCell(x, y, r, attribute(fg), termbox.ColorDefault)
+}
+
+func (t *Termbox) Flush() error {
+	return termbox.Flush()
+}
+
+var (
+	pollOnce sync.Once
+	polled   chan termbox.Event
+)
+
+// Keys reads keys and resizes from termbox. One poller serves every
+// countdown in the process, so a poller left over from the last one does
+// not swallow the keys meant for the next.
+func (t *Termbox) Keys(done <-chan struct{}) <-chan Key {
+	pollOnce.Do(func() {
+		polled = make(chan termbox.Event)
+		go func() {
+			for {
+				polled <- termbox.PollEvent()
+			}
+		}()
+	})
+
+	keys := make(chan Key)
+	go func() {
+		for {
+			var ev termbox.Event
+			select {
+			case <-done:
+				return
+			case ev = <-polled:
+			}
+
+			if ev.Type == termbox.EventResize {
+				select {
+				case t.resized <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
+			k, ok := key(ev)
+			if !ok {
+				continue
+			}
+
+			select {
+			case keys <- k:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+func (t *Termbox) Resized() <-chan struct{} {
+	return t.resized
+}
+
+func attribute(c Color) termbox.Attribute {
+	if v, ok := rgb[c]; ok {
+		return termbox.RGBToAttribute(v[0], v[1], v[2])
+	}
+	return termbox.ColorDefault
+}
+
+// key maps a termbox key event to a Key.
+func key(ev termbox.Event) (Key, bool) {
+	if ev.Type != termbox.EventKey {
+		return 0, false
+	}
+	if ev.Ch != 0 {
+		return Key(ev.Ch), true
+	}
+
+	switch ev.Key {
+	case termbox.KeyEsc:
+		return KeyEsc, true
+	case termbox.KeyCtrlC:
+		return KeyCtrlC, true
+	case termbox.KeyEnter:
+		return KeyEnter, true
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		return KeyBackspace, true
+	case termbox.KeySpace:
+		return ' ', true
+	}
+	return 0, false
+}
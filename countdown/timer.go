@@ -3,158 +3,320 @@ package countdown
 import (
 	"fmt"
 	"time"
+	"unicode/utf8"
 
+	"github.com/aelnahas/pomo/clock"
+	"github.com/aelnahas/pomo/events"
 	"github.com/aelnahas/pomo/sessions"
 	"github.com/aelnahas/pomo/task"
-	"github.com/nsf/termbox-go"
 )
 
+// Countdown is the terminal frontend of an Engine: it turns key presses
+// into commands and draws the clock from the engine's events.
 type Countdown struct {
-	timer        *time.Timer
-	ticker       *time.Ticker
-	queues       chan termbox.Event
-	startDone    bool
-	startX       int
-	startY       int
-	duration     time.Duration
-	task         *task.Task
-	sessiontType sessions.Type
+	*Engine
+
+	screen Renderer
+	last   time.Duration
+	layout layout
+	// prompt is the question being answered: what to do on quitting, or
+	// an interruption's note.
+	prompt string
+	step   time.Duration
 }
 
-const tick = time.Second
+// layout is where the big clock starts. It is kept between frames so the
+// clock does not jitter as narrower digits come and go, and recomputed
+// when the screen or the clock's length changes.
+type layout struct {
+	width, height int
+	length        int
+	x, y          int
+}
 
 var controls = []string{
-	"CTRL-C | ESC -> Quit",
+	"CTRL-C | ESC -> Quit...",
 	"p      | P   -> Pause",
 	"c      | C   -> Continue",
+	"i      | e   -> Log interruption",
+	"+      | s   -> Extend | Skip break",
 }
 
-var (
-	Green  = termbox.RGBToAttribute(154, 255, 0)
-	Red    = termbox.RGBToAttribute(255, 0, 68)
-	Yellow = termbox.RGBToAttribute(255, 196, 0)
-	Cyan   = termbox.RGBToAttribute(0, 239, 255)
-)
+// finish is the extra control shown when counting up.
+const finish = "f      | F   -> Finish"
 
-var colorTypeMap map[sessions.Type]termbox.Attribute = map[sessions.Type]termbox.Attribute{
-	sessions.Focus: termbox.ColorDefault,
+var colorTypeMap map[sessions.Type]Color = map[sessions.Type]Color{
+	sessions.Focus: ColorDefault,
 	sessions.Short: Green,
 	sessions.Long:  Cyan,
 }
 
-func New(d time.Duration, task *task.Task, sessionType sessions.Type) *Countdown {
+// New returns a countdown drawn on screen, or full screen with termbox
+// when screen is nil.
+func New(d time.Duration, task *task.Task, sessionType sessions.Type, bus *events.Bus, screen Renderer) *Countdown {
+	if screen == nil {
+		screen = NewTermbox()
+	}
+
 	return &Countdown{
-		duration:     d,
-		task:         task,
-		sessiontType: sessionType,
+		Engine: NewEngine(d, task, sessionType, bus, clock.Real{}),
+		screen: screen,
+		step:   5 * time.Minute,
+	}
+}
+
+// ExtendBy sets how much + adds to the session, five minutes unless set.
+func (c *Countdown) ExtendBy(d time.Duration) {
+	c.step = d
+}
+
+// render is the terminal's subscription to the bus, it returns once the
+// subscription is closed. Resizes and prompts redraw the last frame
+// straight away.
+func (c *Countdown) render(updates <-chan events.Event, resized <-chan struct{}, prompts <-chan string) {
+	for {
+		select {
+		case e, ok := <-updates:
+			if !ok {
+				return
+			}
+			switch e.Type {
+			case events.Started, events.Tick, events.Paused, events.Resumed, events.Extended:
+				c.last = e.Remaining
+				if c.counting() {
+					c.last = e.Elapsed
+				}
+				c.Draw(c.last)
+			case events.Distracted:
+				c.Draw(c.last)
+			}
+		case <-resized:
+			c.Draw(c.last)
+		case prompt := <-prompts:
+			c.prompt = prompt
+			c.Draw(c.last)
+		}
 	}
 }
 
+// Draw renders one frame: the big clock when the screen has room for it,
+// or a single compact line when it does not. Nothing is drawn outside the
+// screen. d is the time left, or the time spent when counting up.
 func (c *Countdown) Draw(d time.Duration) {
-	w, h := termbox.Size()
-	clear()
+	state := c.State()
+	paused := state.Paused
+
+	w, h := c.screen.Size()
+	c.screen.Clear()
+	defer c.screen.Flush()
+	if w <= 0 || h <= 0 {
+		return
+	}
 
 	str := format(d)
 	text := toText(str)
+	fg := c.color(d, state.Duration)
 
-	fg := colorTypeMap[c.sessiontType]
-	if c.sessiontType == sessions.Focus {
-		remaining := int(100 * float64(d) / float64(c.duration))
-		switch {
-		case remaining > 25 && remaining < 50:
-			fg = Yellow
-		case remaining < 25:
-			fg = Red
-		}
+	description := Symbol([]string{string(c.sessiontType), c.task.Title})
+	escape := c.controls()
+	interruptions := len(c.Interruptions())
+	if w < text.width() || h < text.height()+description.height()+escape.height() {
+		c.drawCompact(w, h, str, fg, paused, interruptions)
+		return
 	}
 
-	if !c.startDone {
-		c.startDone = true
-		c.startX = w/2 - text.width()/2
-		c.startY = h/2 - text.height()/2
+	if c.layout.width != w || c.layout.height != h || c.layout.length != len(str) {
+		c.layout = layout{
+			width:  w,
+			height: h,
+			length: len(str),
+			x:      w/2 - text.width()/2,
+			y:      max(0, min(h/2-text.height()/2-1, h-escape.height()-description.height()-text.height())),
+		}
 	}
 
-	x, y := c.startX, c.startY
+	x, y := c.layout.x, c.layout.y
 	for _, s := range text {
-		echo(s, x, y, fg)
+		echo(c.screen, s, x, y, fg)
 		x += s.width()
 	}
 
-	description := Symbol([]string{string(c.sessiontType), c.task.Title})
 	y += text.height()
-	x = w/2 - description.width()/2
-	echo(description, x, y, termbox.ColorDefault)
-	showControls(w, h)
-	showNumSessions(w, h, c.task.Sessions)
-	flush()
+	for _, line := range description {
+		line = clip(line, w)
+		echo(c.screen, Symbol{line}, w/2-utf8.RuneCountInString(line)/2, y, ColorDefault)
+		y++
+	}
+
+	y++
+	if paused && y < h-escape.height() {
+		indicator := Symbol([]string{"PAUSED"})
+		echo(c.screen, indicator, w/2-indicator.width()/2, y, Yellow)
+		y++
+	}
+	if c.prompt != "" && y < h-escape.height() {
+		line := clip(c.prompt, w)
+		echo(c.screen, Symbol{line}, max(0, w/2-utf8.RuneCountInString(line)/2), y, ColorDefault)
+	}
+	showControls(c.screen, escape, h)
+	showCounts(c.screen, escape, w, h, c.task.Sessions, interruptions)
 }
 
-func showControls(w, h int) {
-	escape := Symbol(controls)
-	echo(escape, 0, h-escape.height(), termbox.ColorDefault)
+// drawCompact fits the clock on one line, with a hint of the controls, or
+// the prompt, underneath when there is a row to spare.
+func (c *Countdown) drawCompact(w, h int, clock string, fg Color, paused bool, interruptions int) {
+	line := []struct {
+		text  string
+		color Color
+	}{
+		{string(c.sessiontType) + " ", ColorDefault},
+		{clock, fg},
+		{" " + c.task.Title, ColorDefault},
+	}
+	if interruptions > 0 {
+		line = append(line, struct {
+			text  string
+			color Color
+		}{fmt.Sprintf(" !%d", interruptions), ColorDefault})
+	}
+	if paused {
+		line = append(line, struct {
+			text  string
+			color Color
+		}{" PAUSED", Yellow})
+	}
+
+	length := 0
+	for _, part := range line {
+		length += utf8.RuneCountInString(part.text)
+	}
+
+	y := h / 2
+	if h > 1 {
+		y = (h - 1) / 2
+	}
+
+	x := max(0, (w-length)/2)
+	for _, part := range line {
+		for _, r := range part.text {
+			if x >= w {
+				break
+			}
+			c.screen.SetCell(x, y, r, part.color)
+			x++
+		}
+	}
+
+	if h > 1 {
+		hint := "p pause  c continue  + extend  s skip break  i/e interruption  esc quit..."
+		if c.counting() {
+			hint = "f finish  " + hint
+		}
+		if c.prompt != "" {
+			hint = c.prompt
+		}
+		hint = clip(hint, w)
+		echo(c.screen, Symbol{hint}, max(0, (w-utf8.RuneCountInString(hint))/2), h-1, ColorDefault)
+	}
 }
 
-func showNumSessions(w, h, sessions int) {
-	symbol := Symbol([]string{fmt.Sprintf("sessions : %d", sessions)})
-	echo(symbol, w-symbol.width(), h-symbol.height(), termbox.ColorDefault)
+func (c *Countdown) controls() Symbol {
+	if c.counting() {
+		return Symbol(append([]string{finish}, controls...))
+	}
+	return Symbol(controls)
 }
 
-func (c *Countdown) start(d time.Duration) {
-	c.timer = time.NewTimer(d)
-	c.ticker = time.NewTicker(tick)
+func (c *Countdown) color(d, duration time.Duration) Color {
+	fg := colorTypeMap[c.sessiontType]
+	if c.sessiontType == sessions.Focus && !c.counting() {
+		remaining := int(100 * float64(d) / float64(duration))
+		switch {
+		case remaining > 25 && remaining < 50:
+			fg = Yellow
+		case remaining < 25:
+			fg = Red
+		}
+	}
+	return fg
 }
 
-func (c *Countdown) stop() {
-	c.timer.Stop()
-	c.ticker.Stop()
+// clip cuts s to at most w runes.
+func clip(s string, w int) string {
+	if utf8.RuneCountInString(s) <= w {
+		return s
+	}
+	return string([]rune(s)[:max(0, w)])
 }
 
-func (c *Countdown) countdown(timeLeft time.Duration, countUp bool) error {
-	c.start(timeLeft)
-	if countUp {
-		timeLeft = 0
+func min(a, b int) int {
+	if a < b {
+		return a
 	}
-	c.Draw(timeLeft)
-	defer termbox.Close()
+	return b
+}
 
-	for {
-		select {
-		case ev := <-c.queues:
-			if ev.Type == termbox.EventKey && (ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyCtrlC) {
-				return fmt.Errorf("Error: timer interrupted")
-			}
-			if ev.Ch == 'p' || ev.Ch == 'P' {
-				c.stop()
-			}
-			if ev.Ch == 'c' || ev.Ch == 'C' {
-				c.start(timeLeft)
-			}
-		case <-c.ticker.C:
-			if countUp {
-				timeLeft += tick
-			} else {
-				timeLeft -= tick
-			}
-			c.Draw(timeLeft)
-		case <-c.timer.C:
-			return nil
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func showControls(screen Renderer, escape Symbol, h int) {
+	echo(screen, escape, 0, h-escape.height(), ColorDefault)
+}
+
+// showCounts goes in the bottom right corner, unless it would run into the
+// controls. Interruptions only show once there are some.
+func showCounts(screen Renderer, escape Symbol, w, h, sessions, interruptions int) {
+	lines := []string{fmt.Sprintf("sessions : %d", sessions)}
+	if interruptions > 0 {
+		lines = []string{
+			fmt.Sprintf("interruptions : %d", interruptions),
+			fmt.Sprintf("sessions      : %d", sessions),
 		}
 	}
+
+	symbol := Symbol(lines)
+	if w-symbol.width() <= escape.width() {
+		return
+	}
+	echo(screen, symbol, w-symbol.width(), h-symbol.height(), ColorDefault)
 }
 
 func (c *Countdown) Run() error {
-	termbox.SetOutputMode(termbox.OutputRGB)
-	if err := termbox.Init(); err != nil {
+	if err := c.screen.Init(); err != nil {
 		return err
 	}
+	defer c.screen.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var commands chan Command
+	prompts := make(chan string)
+	if input, ok := c.screen.(Input); ok {
+		commands = make(chan Command)
+		go c.handle(input.Keys(done), commands, prompts, done)
+	}
 
-	c.queues = make(chan termbox.Event)
+	var resized <-chan struct{}
+	if r, ok := c.screen.(Resizer); ok {
+		resized = r.Resized()
+	}
+
+	updates, unsubscribe := c.bus.Subscribe(16)
+	rendered := make(chan struct{})
 	go func() {
-		for {
-			c.queues <- termbox.PollEvent()
-		}
+		c.render(updates, resized, prompts)
+		close(rendered)
 	}()
-	return c.countdown(c.duration, false)
+
+	err := c.Engine.Run(commands)
+	unsubscribe()
+	<-rendered
+	return err
 }
 
 func format(d time.Duration) string {
@@ -0,0 +1,72 @@
+package countdown
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestDrawLayout(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		sessionType   sessions.Type
+		left          time.Duration
+		countUp       bool
+		interruptions int
+		prompt        string
+	}{
+		{name: "focus_80x24", width: 80, height: 24, sessionType: sessions.Focus, left: 25 * time.Minute},
+		{name: "focus_hours_120x30", width: 120, height: 30, sessionType: sessions.Focus, left: 90 * time.Minute},
+		{name: "short_break_40x14", width: 40, height: 14, sessionType: sessions.Short, left: 5 * time.Minute},
+		{name: "interruptions_80x24", width: 80, height: 24, sessionType: sessions.Focus, left: 10 * time.Minute, interruptions: 2},
+		{name: "prompt_80x24", width: 80, height: 24, sessionType: sessions.Focus, left: 25 * time.Minute, prompt: "(s)ave, (v)oid, or (r)esume?"},
+		{name: "count_up_80x24", width: 80, height: 24, sessionType: sessions.Focus, left: 42 * time.Second, countUp: true},
+		{name: "narrow_30x24", width: 30, height: 24, sessionType: sessions.Focus, left: 25 * time.Minute},
+		{name: "compact_80x5", width: 80, height: 5, sessionType: sessions.Focus, left: 25 * time.Minute, interruptions: 1},
+		{name: "compact_prompt_80x3", width: 80, height: 3, sessionType: sessions.Focus, left: 25 * time.Minute, prompt: "(s)ave, (v)oid, or (r)esume?"},
+		{name: "compact_count_up_60x2", width: 60, height: 2, sessionType: sessions.Focus, left: 42 * time.Second, countUp: true},
+		{name: "compact_20x1", width: 20, height: 1, sessionType: sessions.Long, left: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			screen := NewBuffer(tt.width, tt.height)
+			c := New(25*time.Minute, &task.Task{Title: "write tests", Sessions: 3}, tt.sessionType, nil, screen)
+			if tt.countUp {
+				c.CountUp()
+			}
+			for i := 0; i < tt.interruptions; i++ {
+				c.Log(sessions.Interruption{Kind: sessions.Internal})
+			}
+			c.prompt = tt.prompt
+
+			c.Draw(tt.left)
+			if screen.Frames != 1 {
+				t.Fatalf("flushed %d frames, want 1", screen.Frames)
+			}
+
+			got := screen.String() + "\n"
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("frame differs from %s, got:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -4,14 +4,19 @@ import (
 	"fmt"
 	"os"
 	"unicode/utf8"
-
-	"github.com/nsf/termbox-go"
 )
 
 type Symbol []string
 
+// width is the width of the widest line.
 func (s Symbol) width() int {
-	return utf8.RuneCountInString(s[0])
+	w := 0
+	for _, line := range s {
+		if n := utf8.RuneCountInString(line); n > w {
+			w = n
+		}
+	}
+	return w
 }
 
 func (s Symbol) height() int {
@@ -47,32 +52,6 @@ func toText(str string) Text {
 
 type Font map[rune]Symbol
 
-func echo(s Symbol, startX, startY int, fg termbox.Attribute) {
-	x, y := startX, startY
-
-	for _, line := range s {
-		for _, r := range line {
-			termbox.SetCell(x, y, r, fg, termbox.ColorDefault)
-			x++
-		}
-		x = startX
-		y++
-	}
-}
-
-func clear() {
-	err := termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
-	if err != nil {
-		panic(err)
-	}
-}
-
-func flush() {
-	if err := termbox.Flush(); err != nil {
-		panic(err)
-	}
-}
-
 func stderr(s string, a ...interface{}) {
 	_, err := fmt.Fprintf(os.Stderr, s, a...)
 	if err != nil {
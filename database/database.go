@@ -0,0 +1,81 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// ErrLocked is returned by Open when another process has the database open.
+var ErrLocked = errors.New("database is in use by another pomo process")
+
+type DB struct {
+	*badger.DB
+	Name       string
+	Path       string
+	key        []byte
+	migrations []Migration
+}
+
+func Open(name, path string, key []byte, migrations []Migration) (*DB, error) {
+	db, err := open(path, key)
+	if err != nil {
+		// badger flattens the lock error into its message
+		if strings.Contains(err.Error(), "Another process is using this Badger database") {
+			return nil, fmt.Errorf("%w (%s)", ErrLocked, path)
+		}
+		return nil, err
+	}
+
+	return &DB{
+		DB:         db,
+		Name:       name,
+		Path:       path,
+		key:        key,
+		migrations: migrations,
+	}, nil
+}
+
+func open(path string, key []byte) (*badger.DB, error) {
+	// badger needs an index cache to read encrypted tables, including the ones
+	// left behind while Rekey converts an encrypted database back to plaintext
+	opts := badger.DefaultOptions(path).WithIndexCacheSize(16 << 20)
+	opts.Logger = nil
+	if len(key) > 0 {
+		opts = opts.WithEncryptionKey(key)
+	}
+	return badger.Open(opts)
+}
+
+// CollectGarbage rewrites value log files until badger reports there is
+// nothing left worth rewriting, returning how many files were rewritten.
+func (db *DB) CollectGarbage(discardRatio float64) (int, error) {
+	rewritten := 0
+	for {
+		err := db.RunValueLogGC(discardRatio)
+		if errors.Is(err, badger.ErrNoRewrite) || errors.Is(err, badger.ErrRejected) {
+			return rewritten, nil
+		}
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten++
+	}
+}
+
+func (db *DB) Compact() error {
+	return db.Flatten(runtime.NumCPU())
+}
+
+// Tidy runs a single, cheap value log GC pass. It is meant to be called
+// opportunistically, so it never reports badger declining to rewrite.
+func (db *DB) Tidy() error {
+	err := db.RunValueLogGC(0.7)
+	if errors.Is(err, badger.ErrNoRewrite) || errors.Is(err, badger.ErrRejected) {
+		return nil
+	}
+	return err
+}
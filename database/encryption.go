@@ -0,0 +1,160 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+const keyRotation = 10 * 24 * time.Hour
+
+var (
+	ErrNotEncrypted = errors.New("data is encrypted but the database has no encryption key")
+	ErrWrongKey     = errors.New("data could not be decrypted with the database key")
+)
+
+func (db *DB) Encrypted() bool {
+	return len(db.key) > 0
+}
+
+// Rekey re-encrypts the key registry with a new master key, an empty key
+// turns encryption off. Badger only ever encrypts data keys with the master
+// key, so rotating between two keys is cheap; switching encryption on or off
+// additionally rewrites every record so no plaintext (or ciphertext) is left
+// behind in the old tables.
+func (db *DB) Rekey(key []byte) error {
+	rewrite := db.Encrypted() != (len(key) > 0)
+
+	if err := db.DB.Close(); err != nil {
+		return err
+	}
+
+	opts := badger.KeyRegistryOptions{
+		Dir:                           db.Path,
+		ReadOnly:                      true,
+		EncryptionKey:                 db.key,
+		EncryptionKeyRotationDuration: keyRotation,
+	}
+	registry, err := badger.OpenKeyRegistry(opts)
+	if err != nil {
+		return db.reopen(err)
+	}
+
+	opts.EncryptionKey = key
+	err = badger.WriteKeyRegistry(registry, opts)
+	registry.Close()
+	if err != nil {
+		return db.reopen(err)
+	}
+
+	db.key = key
+	if err := db.reopen(nil); err != nil {
+		return err
+	}
+
+	if rewrite {
+		return db.rewrite()
+	}
+	return nil
+}
+
+// RekeyAll rekeys every database with key. When one fails, the ones already
+// rekeyed are put back on their previous keys, so the databases are never
+// left split across two keys.
+func RekeyAll(key []byte, dbs ...*DB) error {
+	previous := make([][]byte, len(dbs))
+	for i, db := range dbs {
+		previous[i] = db.key
+		if err := db.Rekey(key); err != nil {
+			err = fmt.Errorf("%s: %w", db.Name, err)
+			for j := i - 1; j >= 0; j-- {
+				if undo := dbs[j].Rekey(previous[j]); undo != nil {
+					return fmt.Errorf("%w (restoring the previous key of %s failed: %s)", err, dbs[j].Name, undo)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) reopen(cause error) error {
+	reopened, err := open(db.Path, db.key)
+	if err != nil {
+		return err
+	}
+
+	db.DB = reopened
+	return cause
+}
+
+// rewrite streams every record through memory rather than a temporary file
+// so enabling encryption never leaves a plaintext copy on disk.
+func (db *DB) rewrite() error {
+	var buf bytes.Buffer
+	if _, err := db.Backup(&buf, 0); err != nil {
+		return err
+	}
+
+	if err := db.DropAll(); err != nil {
+		return err
+	}
+
+	return db.Load(&buf, 256)
+}
+
+// Encrypt seals data with the database key so copies of an encrypted
+// database made outside badger, such as backups, stay encrypted too. It
+// returns data unchanged when the database is not encrypted.
+func (db *DB) Encrypt(data []byte) ([]byte, error) {
+	if !db.Encrypted() {
+		return data, nil
+	}
+
+	gcm, err := db.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, data, []byte(db.Name)), nil
+}
+
+func (db *DB) Decrypt(data []byte) ([]byte, error) {
+	if !db.Encrypted() {
+		return nil, ErrNotEncrypted
+	}
+
+	gcm, err := db.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrWrongKey
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(db.Name))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+func (db *DB) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(db.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
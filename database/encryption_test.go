@@ -0,0 +1,80 @@
+package database
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+func openTest(t *testing.T, name, dir string, key []byte) *DB {
+	t.Helper()
+	db, err := Open(name, filepath.Join(dir, name), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRekeyAllRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	tasks := openTest(t, "tasks", dir, oldKey)
+	if err := tasks.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("task"), []byte("write tests"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sessions := openTest(t, "sessions", dir, oldKey)
+
+	// the sessions database thinks it has a key it was not opened with, so
+	// reading its key registry fails
+	sessions.key = bytes.Repeat([]byte{3}, 32)
+
+	if err := RekeyAll(newKey, tasks, sessions); err == nil {
+		t.Fatal("rekeying with a failing database succeeded")
+	}
+	if !bytes.Equal(tasks.key, oldKey) {
+		t.Error("the tasks database was left on the new key")
+	}
+	if err := tasks.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openTest(t, "tasks", dir, oldKey)
+	defer reopened.Close()
+	if err := reopened.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("task"))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			if string(v) != "write tests" {
+				t.Errorf("read %q back", v)
+			}
+			return nil
+		})
+	}); err != nil {
+		t.Fatalf("the tasks database does not open with its previous key: %s", err)
+	}
+}
+
+func TestRekeyAll(t *testing.T) {
+	dir := t.TempDir()
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	tasks := openTest(t, "tasks", dir, nil)
+	sessions := openTest(t, "sessions", dir, nil)
+	if err := RekeyAll(newKey, tasks, sessions); err != nil {
+		t.Fatal(err)
+	}
+	for _, db := range []*DB{tasks, sessions} {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+		openTest(t, db.Name, dir, newKey).Close()
+	}
+}
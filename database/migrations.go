@@ -0,0 +1,161 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var VersionKey = []byte("schema_version")
+
+var ErrNewerSchema = errors.New("database schema is newer than this version of pomo supports")
+
+// Migration moves a database to Version. Up gets the whole database rather
+// than a transaction so migrations that rewrite many records can commit
+// them in batches, the version is only bumped once Up returns without an
+// error.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(db *badger.DB) error
+}
+
+func (db *DB) Latest() int {
+	latest := 0
+	for _, m := range db.migrations {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+func (db *DB) Version() (int, error) {
+	var version int
+	err := db.View(func(txn *badger.Txn) (err error) {
+		version, err = getVersion(txn)
+		return err
+	})
+	return version, err
+}
+
+func (db *DB) Pending() ([]Migration, error) {
+	version, err := db.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if version > db.Latest() {
+		return nil, fmt.Errorf("%s: %w (found v%d, latest v%d)", db.Name, ErrNewerSchema, version, db.Latest())
+	}
+
+	pending := make([]Migration, 0)
+	for _, m := range db.migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func (db *DB) Migrate(backupDir string) ([]Migration, error) {
+	pending, err := db.Pending()
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+
+	version, err := db.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	empty, err := db.isEmpty()
+	if err != nil {
+		return nil, err
+	}
+
+	if !empty {
+		if _, err := db.backupTo(backupDir, fmt.Sprintf("v%d", version)); err != nil {
+			return nil, fmt.Errorf("%s: backup before migrating failed (%w)", db.Name, err)
+		}
+	}
+
+	applied := make([]Migration, 0, len(pending))
+	for _, m := range pending {
+		err := m.Up(db.DB)
+		if err == nil {
+			err = db.Update(func(txn *badger.Txn) error {
+				return txn.Set(VersionKey, []byte(strconv.Itoa(m.Version)))
+			})
+		}
+		if err != nil {
+			return applied, fmt.Errorf("%s: migration v%d failed (%w)", db.Name, m.Version, err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+func (db *DB) backupTo(dir, label string) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.bak", db.Name, label, time.Now().Format("20060102T150405"))
+	path := filepath.Join(dir, name)
+
+	var buf bytes.Buffer
+	if _, err := db.Backup(&buf, 0); err != nil {
+		return "", err
+	}
+
+	data, err := db.Encrypt(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.WriteFile(path, data, 0600)
+}
+
+func (db *DB) isEmpty() (bool, error) {
+	empty := true
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		it.Rewind()
+		empty = !it.Valid()
+		return nil
+	})
+	return empty, err
+}
+
+func getVersion(txn *badger.Txn) (int, error) {
+	item, err := txn.Get(VersionKey)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = item.Value(func(val []byte) (err error) {
+		version, err = strconv.Atoi(string(val))
+		return err
+	})
+	return version, err
+}
+
+func (db *DB) Migrations() []Migration {
+	return db.migrations
+}
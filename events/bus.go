@@ -0,0 +1,149 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+type Type string
+
+const (
+	Started       Type = "started"
+	Tick          Type = "tick"
+	Paused        Type = "paused"
+	Resumed       Type = "resumed"
+	Completed     Type = "completed"
+	Interrupted   Type = "interrupted"
+	TaskChanged   Type = "task_changed"
+	TaskAdded     Type = "task_added"
+	TaskCompleted Type = "task_completed"
+	// Distracted is an interruption logged during a session that carries
+	// on, unlike Interrupted, which ends it.
+	Distracted Type = "distracted"
+	Extended   Type = "extended"
+	Skipped    Type = "skipped"
+)
+
+type Event struct {
+	Type      Type          `json:"type"`
+	At        time.Time     `json:"at"`
+	Session   sessions.Type `json:"session,omitempty"`
+	Task      *task.Task    `json:"task,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Remaining time.Duration `json:"remaining"`
+	Elapsed   time.Duration `json:"elapsed,omitempty"`
+
+	Interruption *sessions.Interruption `json:"interruption,omitempty"`
+}
+
+// TimerState is a snapshot of a running timer, taken by the timer and
+// served by whatever reports on it.
+type TimerState struct {
+	Type      sessions.Type `json:"type"`
+	Task      *task.Task    `json:"task"`
+	Duration  time.Duration `json:"duration"`
+	Remaining time.Duration `json:"remaining"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Paused    bool          `json:"paused"`
+}
+
+// Bus fans events out to every subscriber. A subscriber that falls behind
+// misses ticks rather than stalling the timer, but never anything else:
+// every subscription keeps room for those events on top of its buffer. A
+// subscriber that lets that room run out too is dropped, its channel is
+// closed, so publishing never waits on anyone.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[int]*subscriber
+	next        int
+}
+
+// reserved is the room each subscription keeps for events other than ticks.
+const reserved = 16
+
+type subscriber struct {
+	id int
+	// mu is held while sending on ch, so it is not closed under a send.
+	mu     sync.Mutex
+	ch     chan Event
+	buffer int
+	closed bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]*subscriber)}
+}
+
+// Subscribe returns a channel receiving every event published from now on
+// and a function that unsubscribes and closes the channel. Ticks are
+// dropped while buffer events are waiting to be received, and the channel
+// is closed early if the subscriber falls further behind than that.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscriber{
+		id:     b.next,
+		ch:     make(chan Event, buffer+reserved),
+		buffer: buffer,
+	}
+	b.subscribers[sub.id] = sub
+	b.next++
+
+	return sub.ch, func() {
+		b.drop(sub)
+	}
+}
+
+// drop unsubscribes sub and closes its channel, unless that already
+// happened.
+func (b *Bus) drop(sub *subscriber) {
+	b.mu.Lock()
+	delete(b.subscribers, sub.id)
+	b.mu.Unlock()
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.ch)
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.Lock()
+	subscribers := make([]*subscriber, 0, len(b.subscribers))
+	for _, sub := range b.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subscribers {
+		if !sub.send(e) {
+			b.drop(sub)
+		}
+	}
+}
+
+// send reports false when the subscriber has no room left for e.
+func (s *subscriber) send(e Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || (e.Type == Tick && len(s.ch) >= s.buffer) {
+		return true
+	}
+	select {
+	case s.ch <- e:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishDropsTicksForSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	updates, unsubscribe := bus.Subscribe(2)
+	defer unsubscribe()
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: Tick})
+	}
+	bus.Publish(Event{Type: Completed})
+
+	for _, want := range []Type{Tick, Tick, Completed} {
+		if e := <-updates; e.Type != want {
+			t.Fatalf("got %s, want %s", e.Type, want)
+		}
+	}
+}
+
+func TestPublishDropsStalledSubscribers(t *testing.T) {
+	bus := NewBus()
+	stalled, unsubscribeStalled := bus.Subscribe(1)
+	defer unsubscribeStalled()
+	updates, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i < 1+reserved+1; i++ {
+			bus.Publish(Event{Type: Paused})
+			<-updates
+		}
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing waited on a stalled subscriber")
+	}
+
+	received := 0
+	for range stalled {
+		received++
+	}
+	if received != 1+reserved {
+		t.Errorf("the stalled subscriber received %d events before being dropped, want %d", received, 1+reserved)
+	}
+
+	// unsubscribing after being dropped is harmless
+	unsubscribeStalled()
+	bus.Publish(Event{Type: Resumed})
+	if e := <-updates; e.Type != Resumed {
+		t.Errorf("the other subscriber got %s, want %s", e.Type, Resumed)
+	}
+}
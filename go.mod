@@ -5,9 +5,12 @@ go 1.17
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/dgraph-io/badger/v3 v3.2103.2
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/google/uuid v1.3.0
 	github.com/nsf/termbox-go v1.1.1
 	github.com/spf13/cobra v1.4.0
+	golang.org/x/crypto v0.0.0-20220411220226-7b82a4e95df4
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
 )
 
 require (
@@ -0,0 +1,270 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+)
+
+type Name string
+
+const (
+	OnStart        Name = "on_start"
+	OnPause        Name = "on_pause"
+	OnResume       Name = "on_resume"
+	OnComplete     Name = "on_complete"
+	OnInterrupt    Name = "on_interrupt"
+	OnBreakStart   Name = "on_break_start"
+	OnTaskComplete Name = "on_task_complete"
+)
+
+const DefaultTimeout = 10 * time.Second
+
+// Payload is written as json to the hook's stdin.
+type Payload struct {
+	Hook Name `json:"hook"`
+	events.Event
+}
+
+// Runner executes the shell command configured for a hook. Commands run
+// with their output captured, so they never draw over the timer, and
+// failures are appended to a log file instead.
+type Runner struct {
+	commands map[Name]string
+	timeout  time.Duration
+	logPath  string
+}
+
+func New(commands map[Name]string, timeout time.Duration, logPath string) *Runner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	configured := make(map[Name]string, len(commands))
+	for name, command := range commands {
+		if strings.TrimSpace(command) != "" {
+			configured[name] = command
+		}
+	}
+
+	return &Runner{commands: configured, timeout: timeout, logPath: logPath}
+}
+
+// Enabled reports whether any hook has a command.
+func (r *Runner) Enabled() bool {
+	return len(r.commands) > 0
+}
+
+// For maps a bus event to the hook it triggers, if any.
+func For(e events.Event) (Name, bool) {
+	switch e.Type {
+	case events.Started:
+		if e.Session == sessions.Focus {
+			return OnStart, true
+		}
+		return OnBreakStart, true
+	case events.Paused:
+		return OnPause, true
+	case events.Resumed:
+		return OnResume, true
+	case events.Completed:
+		return OnComplete, true
+	case events.Interrupted:
+		return OnInterrupt, true
+	case events.TaskCompleted:
+		return OnTaskComplete, true
+	}
+	return "", false
+}
+
+// Watch runs hooks for events published on bus, one at a time and in order.
+// Events with a hook are queued as they arrive and the hooks run apart from
+// the subscription, so a slow hook cannot make it fall behind. The returned
+// function unsubscribes and waits for queued hooks to finish.
+func (r *Runner) Watch(bus *events.Bus) func() {
+	updates, unsubscribe := bus.Subscribe(64)
+
+	var (
+		mu     sync.Mutex
+		queued []events.Event
+	)
+	wake := make(chan struct{}, 1)
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		for e := range updates {
+			if _, ok := For(e); !ok {
+				continue
+			}
+
+			mu.Lock()
+			queued = append(queued, e)
+			mu.Unlock()
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			mu.Lock()
+			batch := queued
+			queued = nil
+			mu.Unlock()
+
+			for _, e := range batch {
+				name, _ := For(e)
+				r.Run(name, e)
+			}
+			if len(batch) > 0 {
+				continue
+			}
+
+			select {
+			case <-wake:
+			case <-read:
+				mu.Lock()
+				idle := len(queued) == 0
+				mu.Unlock()
+				if idle {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		<-done
+	}
+}
+
+// Run executes the command for name, if one is configured. Failures are
+// logged and returned.
+func (r *Runner) Run(name Name, e events.Event) error {
+	command, ok := r.commands[name]
+	if !ok {
+		return nil
+	}
+
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	payload, err := json.Marshal(Payload{Hook: name, Event: e})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	// Output goes to a file rather than a pipe so that background children
+	// holding it open cannot keep Run waiting past the timeout.
+	out, err := os.CreateTemp("", "pomo-hook-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), environ(name, e)...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if err != nil {
+		err = fmt.Errorf("hook %s failed (%w)", name, err)
+		r.logFailure(err, command, output(out))
+	}
+	return err
+}
+
+func output(f *os.File) string {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	data, _ := io.ReadAll(io.LimitReader(f, 4096))
+	return string(data)
+}
+
+func (r *Runner) logFailure(err error, command, output string) {
+	if r.logPath == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.logPath), 0o700); err != nil {
+		return
+	}
+
+	f, ferr := os.OpenFile(r.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if ferr != nil {
+		return
+	}
+	defer f.Close()
+
+	logger := log.New(f, "", log.LstdFlags)
+	logger.Printf("%s: %s", err, command)
+	if output = strings.TrimSpace(output); output != "" {
+		logger.Printf("output: %s", output)
+	}
+}
+
+func environ(name Name, e events.Event) []string {
+	env := []string{
+		"POMO_HOOK=" + string(name),
+		"POMO_EVENT=" + string(e.Type),
+		"POMO_AT=" + e.At.Format(time.RFC3339),
+	}
+
+	if e.Session != "" {
+		env = append(env, "POMO_SESSION="+string(e.Session))
+	}
+	if e.Duration > 0 {
+		env = append(env, "POMO_DURATION="+seconds(e.Duration))
+	}
+	env = append(env, "POMO_REMAINING="+seconds(e.Remaining))
+	if e.Elapsed > 0 {
+		env = append(env, "POMO_ELAPSED="+seconds(e.Elapsed))
+	}
+
+	if e.Task != nil {
+		env = append(env,
+			"POMO_TASK_ID="+e.Task.ID.String(),
+			"POMO_TASK_TITLE="+e.Task.Title,
+			"POMO_TASK_STATUS="+string(e.Task.Status),
+			"POMO_TASK_SESSIONS="+strconv.Itoa(e.Task.Sessions),
+		)
+		if len(e.Task.Projects) > 0 {
+			env = append(env, "POMO_TASK_PROJECTS="+strings.Join(e.Task.Projects, ","))
+		}
+	}
+	return env
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d.Round(time.Second)/time.Second), 10)
+}
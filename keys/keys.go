@@ -0,0 +1,162 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	EnvKey           = "POMO_ENCRYPTION_KEY"
+	EnvPassphrase    = "POMO_PASSPHRASE"
+	EnvNewPassphrase = "POMO_NEW_PASSPHRASE"
+	Size             = 32
+	saltSize         = 16
+)
+
+var ErrNoTerminal = errors.New("cannot prompt for a passphrase without a terminal")
+
+// Load resolves the master key from, in order, the keyfile, the
+// POMO_ENCRYPTION_KEY variable, and a passphrase taken from POMO_PASSPHRASE or
+// prompted for, stretched with scrypt using the salt stored at saltPath.
+func Load(keyfile, saltPath string) ([]byte, error) {
+	if keyfile != "" {
+		return FromFile(keyfile)
+	}
+
+	if value, ok := os.LookupEnv(EnvKey); ok {
+		return Parse([]byte(value))
+	}
+
+	passphrase, err := Passphrase(EnvPassphrase, "pomo passphrase: ", false)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := Salt(saltPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return Derive(passphrase, salt)
+}
+
+func FromFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// RawPrefix marks a key given as the key bytes themselves rather than hex.
+const RawPrefix = "raw:"
+
+// Parse reads a key written as hex, surrounding whitespace aside, or as its
+// bytes following RawPrefix, where only a trailing line ending is dropped.
+func Parse(data []byte) ([]byte, error) {
+	var key []byte
+	if bytes.HasPrefix(data, []byte(RawPrefix)) {
+		key = bytes.TrimSuffix(bytes.TrimSuffix(data[len(RawPrefix):], []byte("\n")), []byte("\r"))
+	} else {
+		decoded, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+		if err != nil {
+			return nil, fmt.Errorf("encryption key must be hex, or its bytes prefixed with %q (%w)", RawPrefix, err)
+		}
+		key = decoded
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("encryption key must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+func Generate(path string) ([]byte, error) {
+	key := make([]byte, Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func Derive(passphrase, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, Size)
+}
+
+func Salt(path string) ([]byte, error) {
+	salt, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewSalt(path)
+	}
+	return salt, err
+}
+
+func NewSalt(path string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	return salt, os.WriteFile(path, salt, 0600)
+}
+
+// Passphrase reads a passphrase from env, falling back to prompting on the
+// terminal, asking twice when confirm is set.
+func Passphrase(env, prompt string, confirm bool) ([]byte, error) {
+	if value, ok := os.LookupEnv(env); ok {
+		return []byte(value), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, ErrNoTerminal
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "confirm: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
@@ -0,0 +1,185 @@
+package maintenance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+type Kind string
+
+const (
+	InvalidRecord    Kind = "invalid-record"
+	DanglingCurrent  Kind = "dangling-current"
+	MalformedHistory Kind = "malformed-history"
+	// OrphanedHistory is history of a task that has been removed. It is
+	// only reported, the history still counts towards stats and exports.
+	OrphanedHistory Kind = "orphaned-history"
+)
+
+// Repairable reports whether Repair deletes problems of this kind.
+func (k Kind) Repairable() bool {
+	return k != OrphanedHistory
+}
+
+type Problem struct {
+	Database *database.DB
+	Key      []byte
+	Kind     Kind
+	Detail   string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s\t%s\t%s\t%s", p.Database.Name, p.Kind, p.Key, p.Detail)
+}
+
+// Check walks both databases and reports records that no longer decode,
+// a current task pointer to a task that is gone, session history that
+// cannot belong to any task, and history of tasks that have been removed.
+func Check(tasks, sessionsDB *database.DB) ([]Problem, error) {
+	problems := make([]Problem, 0)
+	known := make(map[uuid.UUID]bool)
+
+	err := tasks.View(func(txn *badger.Txn) error {
+		var current []byte
+		err := each(txn, func(key, val []byte) {
+			switch {
+			case bytes.Equal(key, task.CurrentTaskKey):
+				current = append([]byte{}, val...)
+			case isUUID(key):
+				var t task.Task
+				if err := json.Unmarshal(val, &t); err != nil {
+					problems = append(problems, Problem{tasks, key, InvalidRecord, err.Error()})
+					return
+				}
+				known[t.ID] = true
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if current == nil {
+			return nil
+		}
+
+		id, err := uuid.ParseBytes(current)
+		if err != nil || !known[id] {
+			problems = append(problems, Problem{tasks, task.CurrentTaskKey, DanglingCurrent, fmt.Sprintf("points to %s", current)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = sessionsDB.View(func(txn *badger.Txn) error {
+		return each(txn, func(key, val []byte) {
+			switch {
+			case bytes.Equal(key, sessions.Key):
+				var s sessions.Session
+				if err := json.Unmarshal(val, &s); err != nil {
+					problems = append(problems, Problem{sessionsDB, key, InvalidRecord, err.Error()})
+				}
+			case bytes.HasPrefix(key, sessions.HistoryPrefix):
+				var r sessions.Record
+				if err := json.Unmarshal(val, &r); err != nil {
+					problems = append(problems, Problem{sessionsDB, key, InvalidRecord, err.Error()})
+					return
+				}
+				if detail := malformed(key, r); detail != "" {
+					problems = append(problems, Problem{sessionsDB, key, MalformedHistory, detail})
+					return
+				}
+				if !known[r.TaskID] {
+					problems = append(problems, Problem{sessionsDB, key, OrphanedHistory, fmt.Sprintf("task %s was removed", r.TaskID)})
+				}
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return problems, nil
+}
+
+// Repairable returns the problems Repair deletes.
+func Repairable(problems []Problem) []Problem {
+	repairable := make([]Problem, 0, len(problems))
+	for _, p := range problems {
+		if p.Kind.Repairable() {
+			repairable = append(repairable, p)
+		}
+	}
+	return repairable
+}
+
+// Repair deletes the records reported by Check, except for the kinds that
+// are only reported. Callers are expected to take a backup first.
+func Repair(problems ...Problem) error {
+	for _, p := range Repairable(problems) {
+		err := p.Database.Update(func(txn *badger.Txn) error {
+			err := txn.Delete(p.Key)
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func each(txn *badger.Txn, fn func(key, val []byte)) error {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		key := item.KeyCopy(nil)
+		err := item.Value(func(val []byte) error {
+			fn(key, val)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// malformed says what is wrong with a history record that is not stored
+// under history/<start>/<id>, or has no task, and is empty otherwise.
+func malformed(key []byte, r sessions.Record) string {
+	parts := bytes.Split(bytes.TrimPrefix(key, sessions.HistoryPrefix), []byte("/"))
+	if len(parts) != 2 || len(parts[0]) == 0 || !isUUID(parts[1]) {
+		return "key is not history/<start>/<id>"
+	}
+	for _, c := range parts[0] {
+		if c < '0' || c > '9' {
+			return "key is not history/<start>/<id>"
+		}
+	}
+	if !bytes.Equal(parts[1], []byte(r.ID.String())) {
+		return fmt.Sprintf("key does not match record id %s", r.ID)
+	}
+	if r.TaskID == uuid.Nil {
+		return "record has no task id"
+	}
+	return ""
+}
+
+func isUUID(key []byte) bool {
+	_, err := uuid.ParseBytes(key)
+	return err == nil
+}
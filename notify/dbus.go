@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	appName = "pomo"
+
+	busName    = "org.freedesktop.Notifications"
+	objectPath = dbus.ObjectPath("/org/freedesktop/Notifications")
+
+	actionInvoked      = busName + ".ActionInvoked"
+	notificationClosed = busName + ".NotificationClosed"
+)
+
+// Desktop sends freedesktop notifications over D-Bus.
+type Desktop struct {
+	conn    *dbus.Conn
+	actions bool
+}
+
+// SessionBus connects to the session bus named by
+// DBUS_SESSION_BUS_ADDRESS and checks a notification server is running.
+func SessionBus() (*Desktop, error) {
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	d, err := NewDesktop(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewDesktop uses an existing connection, such as one to a stand-in bus.
+func NewDesktop(conn *dbus.Conn) (*Desktop, error) {
+	var capabilities []string
+	err := conn.Object(busName, objectPath).Call(busName+".GetCapabilities", 0).Store(&capabilities)
+	if err != nil {
+		return nil, fmt.Errorf("no notification server on the session bus (%w)", err)
+	}
+
+	d := &Desktop{conn: conn}
+	for _, c := range capabilities {
+		if c == "actions" {
+			d.actions = true
+		}
+	}
+	return d, nil
+}
+
+func (d *Desktop) Actions() bool {
+	return d.actions
+}
+
+func (d *Desktop) Close() error {
+	return d.conn.Close()
+}
+
+func (d *Desktop) Notify(ctx context.Context, n Notification) (string, error) {
+	var actions []string
+	if d.actions {
+		for _, a := range n.Actions {
+			actions = append(actions, a.Key, a.Label)
+		}
+	}
+
+	// Subscribe before sending so a quick click is not missed.
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(objectPath),
+		dbus.WithMatchInterface(busName),
+	}
+	if err := d.conn.AddMatchSignal(match...); err != nil {
+		return "", err
+	}
+	defer d.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 8)
+	d.conn.Signal(signals)
+	defer d.conn.RemoveSignal(signals)
+
+	hints := map[string]dbus.Variant{
+		"category": dbus.MakeVariant("presence"),
+	}
+	if len(actions) > 0 {
+		hints["resident"] = dbus.MakeVariant(true)
+	}
+
+	var id uint32
+	call := d.conn.Object(busName, objectPath).CallWithContext(ctx, busName+".Notify", 0,
+		appName, uint32(0), "", n.Summary, n.Body, actions, hints, int32(-1))
+	if err := call.Store(&id); err != nil {
+		return "", err
+	}
+
+	if len(actions) == 0 {
+		return "", nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.conn.Object(busName, objectPath).Call(busName+".CloseNotification", 0, id)
+			return "", nil
+		case signal, ok := <-signals:
+			if !ok {
+				return "", nil
+			}
+			if len(signal.Body) < 2 {
+				continue
+			}
+			if sid, ok := signal.Body[0].(uint32); !ok || sid != id {
+				continue
+			}
+
+			switch signal.Name {
+			case actionInvoked:
+				key, _ := signal.Body[1].(string)
+				d.conn.Object(busName, objectPath).Call(busName+".CloseNotification", 0, id)
+				return key, nil
+			case notificationClosed:
+				return "", nil
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// sessionBus starts a private bus for the test and returns its address.
+func sessionBus(t *testing.T) string {
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	cmd := exec.Command(path, "--session", "--nofork", "--print-address=1")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the bus address: %s", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+func connect(t *testing.T, addr string) *dbus.Conn {
+	conn, err := dbus.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.Auth(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Hello(); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// standIn is a notification server on the test bus. answer decides what
+// happens to each notification once it is shown.
+type standIn struct {
+	conn         *dbus.Conn
+	capabilities []string
+	answer       func(s *standIn, id uint32)
+
+	mu     sync.Mutex
+	next   uint32
+	shown  []shown
+	closed []uint32
+}
+
+type shown struct {
+	id      uint32
+	summary string
+	body    string
+	actions []string
+	hints   map[string]dbus.Variant
+}
+
+func newStandIn(t *testing.T, addr string, answer func(s *standIn, id uint32), capabilities ...string) *standIn {
+	s := &standIn{conn: connect(t, addr), capabilities: capabilities, answer: answer}
+	if err := s.conn.Export(s, objectPath, busName); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := s.conn.RequestName(busName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("could not own %s", busName)
+	}
+	return s
+}
+
+func (s *standIn) GetCapabilities() ([]string, *dbus.Error) {
+	return s.capabilities, nil
+}
+
+func (s *standIn) Notify(app string, replaces uint32, icon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	s.next++
+	id := s.next
+	s.shown = append(s.shown, shown{id: id, summary: summary, body: body, actions: actions, hints: hints})
+	s.mu.Unlock()
+
+	if s.answer != nil {
+		go s.answer(s, id)
+	}
+	return id, nil
+}
+
+func (s *standIn) CloseNotification(id uint32) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = append(s.closed, id)
+	return nil
+}
+
+func (s *standIn) invoke(id uint32, key string) {
+	s.conn.Emit(objectPath, actionInvoked, id, key)
+}
+
+func (s *standIn) dismiss(id uint32) {
+	s.conn.Emit(objectPath, notificationClosed, id, uint32(2))
+}
+
+func (s *standIn) notifications() []shown {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]shown(nil), s.shown...)
+}
+
+func (s *standIn) closedIDs() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint32(nil), s.closed...)
+}
+
+var sessionEnded = Notification{
+	Summary: "focus session complete",
+	Body:    "write tests, next up: short break",
+	Actions: []Action{
+		{Key: StartNext, Label: "Start break"},
+		{Key: Snooze, Label: "Snooze 5m"},
+	},
+}
+
+func newTestDesktop(t *testing.T, addr string) *Desktop {
+	d, err := NewDesktop(connect(t, addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDesktopReturnsInvokedAction(t *testing.T) {
+	addr := sessionBus(t)
+	server := newStandIn(t, addr, func(s *standIn, id uint32) {
+		// an action on someone else's notification is not ours
+		s.invoke(id+100, StartNext)
+		s.invoke(id, Snooze)
+	}, "body", "actions")
+
+	d := newTestDesktop(t, addr)
+	if !d.Actions() {
+		t.Fatal("the server offers actions but the desktop does not use them")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	action, err := d.Notify(ctx, sessionEnded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != Snooze {
+		t.Errorf("action is %q, want %q", action, Snooze)
+	}
+
+	notes := server.notifications()
+	if len(notes) != 1 {
+		t.Fatalf("%d notifications shown, want 1", len(notes))
+	}
+	n := notes[0]
+	if n.summary != sessionEnded.Summary || n.body != sessionEnded.Body {
+		t.Errorf("shown %q / %q", n.summary, n.body)
+	}
+	want := []string{StartNext, "Start break", Snooze, "Snooze 5m"}
+	if strings.Join(n.actions, "|") != strings.Join(want, "|") {
+		t.Errorf("actions are %q, want %q", n.actions, want)
+	}
+	if resident, ok := n.hints["resident"]; !ok || resident.Value() != true {
+		t.Error("a notification with actions is not resident")
+	}
+	if closed := server.closedIDs(); len(closed) != 1 || closed[0] != n.id {
+		t.Errorf("closed %v, want the answered notification %d", closed, n.id)
+	}
+}
+
+func TestDesktopDismissed(t *testing.T) {
+	addr := sessionBus(t)
+	newStandIn(t, addr, func(s *standIn, id uint32) {
+		s.dismiss(id)
+	}, "actions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	action, err := newTestDesktop(t, addr).Notify(ctx, sessionEnded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != "" {
+		t.Errorf("dismissing answered %q", action)
+	}
+	if ctx.Err() != nil {
+		t.Error("dismissing did not end the wait")
+	}
+}
+
+func TestDesktopTimesOut(t *testing.T) {
+	addr := sessionBus(t)
+	server := newStandIn(t, addr, nil, "actions")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	action, err := newTestDesktop(t, addr).Notify(ctx, sessionEnded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != "" {
+		t.Errorf("an unanswered notification answered %q", action)
+	}
+
+	// the unanswered notification is taken down
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.closedIDs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if closed := server.closedIDs(); len(closed) != 1 || closed[0] != server.notifications()[0].id {
+		t.Errorf("closed %v after timing out", closed)
+	}
+}
+
+func TestDesktopWithoutActions(t *testing.T) {
+	addr := sessionBus(t)
+	server := newStandIn(t, addr, nil, "body")
+
+	d := newTestDesktop(t, addr)
+	if d.Actions() {
+		t.Fatal("the server offers no actions but the desktop uses them")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	action, err := d.Notify(ctx, sessionEnded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != "" || ctx.Err() != nil {
+		t.Errorf("answered %q, waited %v, want no answer and no wait", action, ctx.Err())
+	}
+
+	notes := server.notifications()
+	if len(notes) != 1 || len(notes[0].actions) != 0 {
+		t.Fatalf("shown %+v, want one notification without actions", notes)
+	}
+	if _, ok := notes[0].hints["resident"]; ok {
+		t.Error("a notification without actions is resident")
+	}
+}
+
+func TestNewDesktopWithoutServer(t *testing.T) {
+	addr := sessionBus(t)
+	if _, err := NewDesktop(connect(t, addr)); err == nil {
+		t.Error("connected to a bus with no notification server")
+	}
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+const (
+	Auto       = "auto"
+	DBus       = "dbus"
+	NotifySend = "notify-send"
+	Bell       = "bell"
+	None       = "none"
+)
+
+// Actions offered when a session ends.
+const (
+	StartNext = "start-next"
+	Snooze    = "snooze"
+)
+
+type Action struct {
+	Key   string
+	Label string
+}
+
+type Notification struct {
+	Summary string
+	Body    string
+	Actions []Action
+}
+
+// Notifier shows a notification and, when the backend supports actions,
+// blocks until one is chosen, the notification is dismissed or ctx is done.
+// It returns the key of the chosen action, or "" when there is none.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) (string, error)
+	Actions() bool
+}
+
+// New returns the notifier for backend. Auto picks the first one available
+// of D-Bus, notify-send and the terminal bell.
+func New(backend string, bell io.Writer) (Notifier, error) {
+	switch backend {
+	case "", Auto:
+		if n, err := SessionBus(); err == nil {
+			return n, nil
+		}
+		if path, err := exec.LookPath(NotifySend); err == nil {
+			return &Command{path: path}, nil
+		}
+		return &Terminal{out: bell}, nil
+	case DBus:
+		return SessionBus()
+	case NotifySend:
+		path, err := exec.LookPath(NotifySend)
+		if err != nil {
+			return nil, err
+		}
+		return &Command{path: path}, nil
+	case Bell:
+		return &Terminal{out: bell}, nil
+	case None:
+		return Nop{}, nil
+	}
+	return nil, fmt.Errorf("unknown notification backend %q", backend)
+}
+
+// Command shows notifications through notify-send, without actions.
+type Command struct {
+	path string
+}
+
+func (c *Command) Notify(ctx context.Context, n Notification) (string, error) {
+	return "", exec.CommandContext(ctx, c.path, "--app-name", appName, n.Summary, n.Body).Run()
+}
+
+func (c *Command) Actions() bool {
+	return false
+}
+
+// Terminal rings the terminal bell.
+type Terminal struct {
+	out io.Writer
+}
+
+func (t *Terminal) Notify(ctx context.Context, n Notification) (string, error) {
+	out := t.out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err := fmt.Fprint(out, "\a")
+	return "", err
+}
+
+func (t *Terminal) Actions() bool {
+	return false
+}
+
+type Nop struct{}
+
+func (Nop) Notify(ctx context.Context, n Notification) (string, error) {
+	return "", nil
+}
+
+func (Nop) Actions() bool {
+	return false
+}
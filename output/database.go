@@ -0,0 +1,42 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/aelnahas/pomo/maintenance"
+)
+
+var migrationsHeader = []string{"database", "version", "description", "state"}
+var problemsHeader = []string{"database", "problem", "key", "detail"}
+
+func PrintMigrations(name string, current int, migrations ...database.Migration) {
+	if len(migrations) == 0 {
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, strings.Join(migrationsHeader, "\t"))
+	for _, m := range migrations {
+		state := "pending"
+		if m.Version <= current {
+			state = "applied"
+		}
+		fmt.Fprintln(writer, strings.Join([]string{name, fmt.Sprintf("v%d", m.Version), m.Description, state}, "\t"))
+	}
+}
+
+func PrintProblems(problems ...maintenance.Problem) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, strings.Join(problemsHeader, "\t"))
+	for _, p := range problems {
+		fmt.Fprintln(writer, p.String())
+	}
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/google/uuid"
+)
+
+var sessionInterruptionsHeader = []string{"started", "type", "task", "duration", "internal", "external", "notes"}
+var taskInterruptionsHeader = []string{"task", "focus sessions", "internal", "external", "per session"}
+
+func PrintSessionInterruptions(titles map[uuid.UUID]string, records ...sessions.Record) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, strings.Join(sessionInterruptionsHeader, "\t"))
+	for _, r := range records {
+		notes := make([]string, 0, len(r.Interruptions))
+		for _, i := range r.Interruptions {
+			if i.Note != "" {
+				notes = append(notes, i.Note)
+			}
+		}
+
+		fmt.Fprintln(writer, strings.Join([]string{
+			r.StartedAt.Format("2006-01-02 15:04"),
+			r.Label(),
+			title(titles, r.TaskID),
+			r.Duration.Round(time.Second).String(),
+			fmt.Sprint(r.Interrupted(sessions.Internal)),
+			fmt.Sprint(r.Interrupted(sessions.External)),
+			strings.Join(notes, "; "),
+		}, "\t"))
+	}
+}
+
+// PrintTaskInterruptions totals interruptions by task, averaging them over
+// the task's focus sessions.
+func PrintTaskInterruptions(titles map[uuid.UUID]string, records ...sessions.Record) {
+	type totals struct {
+		focus, internal, external int
+	}
+
+	byTask := make(map[uuid.UUID]*totals)
+	for _, r := range records {
+		t, ok := byTask[r.TaskID]
+		if !ok {
+			t = &totals{}
+			byTask[r.TaskID] = t
+		}
+		if r.Type == sessions.Focus {
+			t.focus++
+		}
+		t.internal += r.Interrupted(sessions.Internal)
+		t.external += r.Interrupted(sessions.External)
+	}
+
+	ids := make([]uuid.UUID, 0, len(byTask))
+	for id := range byTask {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return title(titles, ids[i]) < title(titles, ids[j])
+	})
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, strings.Join(taskInterruptionsHeader, "\t"))
+	for _, id := range ids {
+		t := byTask[id]
+		perSession := "-"
+		if t.focus > 0 {
+			perSession = fmt.Sprintf("%.1f", float64(t.internal+t.external)/float64(t.focus))
+		}
+
+		fmt.Fprintln(writer, strings.Join([]string{
+			title(titles, id),
+			fmt.Sprint(t.focus),
+			fmt.Sprint(t.internal),
+			fmt.Sprint(t.external),
+			perSession,
+		}, "\t"))
+	}
+}
+
+// title names a task, falling back to its id once it has been removed.
+func title(titles map[uuid.UUID]string, id uuid.UUID) string {
+	if t, ok := titles[id]; ok {
+		return t
+	}
+	return id.String()
+}
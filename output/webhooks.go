@@ -0,0 +1,30 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aelnahas/pomo/webhooks"
+)
+
+var deliveriesHeader = []string{"id", "event", "url", "attempts", "next attempt", "last error"}
+
+func PrintDeliveries(deliveries ...webhooks.Delivery) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, strings.Join(deliveriesHeader, "\t"))
+	for _, d := range deliveries {
+		fmt.Fprintln(writer, strings.Join([]string{
+			d.ID,
+			d.Event,
+			d.URL,
+			fmt.Sprint(d.Attempts),
+			d.NextAttempt.Format(time.RFC3339),
+			d.LastError,
+		}, "\t"))
+	}
+}
@@ -0,0 +1,225 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+var HistoryPrefix = []byte("history/")
+
+// historyIDPrefix indexes history records by id, each key holds the key of
+// the record with that id.
+var historyIDPrefix = []byte("history-id/")
+
+type Record struct {
+	ID        uuid.UUID     `json:"id"`
+	Type      Type          `json:"type"`
+	TaskID    uuid.UUID     `json:"task_id"`
+	StartedAt time.Time     `json:"started_at"`
+	EndedAt   time.Time     `json:"ended_at"`
+	Duration  time.Duration `json:"duration"`
+	Paused    time.Duration `json:"paused,omitempty"`
+	Pauses    []Pause       `json:"pauses,omitempty"`
+	// Abandoned sessions were given up before they ran their course,
+	// skipped breaks were cut short to get back to work.
+	Abandoned  bool        `json:"abandoned,omitempty"`
+	Skipped    bool        `json:"skipped,omitempty"`
+	Extensions []Extension `json:"extensions,omitempty"`
+
+	Interruptions []Interruption `json:"interruptions,omitempty"`
+}
+
+// Pause is a stretch of a session spent paused. End is zero while the
+// pause is ongoing.
+type Pause struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Extension is time added to a session while it ran.
+type Extension struct {
+	At time.Time     `json:"at"`
+	By time.Duration `json:"by"`
+}
+
+type InterruptionKind string
+
+const (
+	Internal InterruptionKind = "internal"
+	External InterruptionKind = "external"
+)
+
+// Interruption is something that broke concentration during a session
+// without ending it: an urge from within, or someone or something else.
+type Interruption struct {
+	At   time.Time        `json:"at"`
+	Kind InterruptionKind `json:"kind"`
+	Note string           `json:"note,omitempty"`
+}
+
+func NewRecord(sessionType Type, taskID uuid.UUID, startedAt time.Time) *Record {
+	return &Record{
+		ID:        uuid.New(),
+		Type:      sessionType,
+		TaskID:    taskID,
+		StartedAt: startedAt,
+	}
+}
+
+// End closes the record at the given time. Duration counts only the time
+// the timer was running, pauses are totalled separately.
+func (r *Record) End(at time.Time) {
+	r.EndedAt = at
+	r.Paused = 0
+	for _, p := range r.Pauses {
+		end := p.End
+		if end.IsZero() {
+			end = at
+		}
+		r.Paused += end.Sub(p.Start)
+	}
+	r.Duration = at.Sub(r.StartedAt) - r.Paused
+}
+
+// Label is the record's type, marked when the session was abandoned or
+// skipped.
+func (r Record) Label() string {
+	switch {
+	case r.Abandoned:
+		return string(r.Type) + " (abandoned)"
+	case r.Skipped:
+		return string(r.Type) + " (skipped)"
+	}
+	return string(r.Type)
+}
+
+// Interrupted counts the record's interruptions of the given kind.
+func (r Record) Interrupted(kind InterruptionKind) int {
+	n := 0
+	for _, i := range r.Interruptions {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (r Record) Key() []byte {
+	return []byte(fmt.Sprintf("%s%020d/%s", HistoryPrefix, r.StartedAt.UnixNano(), r.ID))
+}
+
+func idKey(id uuid.UUID) []byte {
+	return append(append([]byte{}, historyIDPrefix...), id.String()...)
+}
+
+// Record writes r, replacing any record with the same id. The start time is
+// part of the key, so the record's current key is looked up by id to
+// remove a copy written with a different start.
+func (s *store) Record(r Record) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		key := r.Key()
+		item, err := txn.Get(idKey(r.ID))
+		switch {
+		case err == nil:
+			previous, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(previous, key) {
+				if err := txn.Delete(previous); err != nil {
+					return err
+				}
+			}
+		case !errors.Is(err, badger.ErrKeyNotFound):
+			return err
+		}
+
+		if err := txn.Set(key, data); err != nil {
+			return err
+		}
+		return txn.Set(idKey(r.ID), key)
+	})
+}
+
+// indexHistory writes the id index for every history record. Keys sort by
+// start time, so a record stored twice under one id is indexed by its
+// latest copy.
+func indexHistory(db *badger.DB) error {
+	index := make(map[uuid.UUID][]byte)
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = HistoryPrefix
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			id, err := uuid.ParseBytes(key[bytes.LastIndexByte(key, '/')+1:])
+			if err != nil {
+				// left for db check to report
+				continue
+			}
+			index[id] = key
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for id, key := range index {
+		if err := batch.Set(idKey(id), key); err != nil {
+			return err
+		}
+	}
+	return batch.Flush()
+}
+
+func (s *store) History() ([]Record, error) {
+	records := make([]Record, 0)
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = HistoryPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var r Record
+				if err := json.Unmarshal(val, &r); err != nil {
+					return err
+				}
+
+				records = append(records, r)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
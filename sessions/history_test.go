@@ -0,0 +1,83 @@
+package sessions
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+func newTestStore(t *testing.T) *store {
+	t.Helper()
+	db, err := database.Open("sessions", t.TempDir(), nil, Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewStoreOn(db, 4)
+}
+
+func TestRecordReplacesByID(t *testing.T) {
+	s := newTestStore(t)
+	start := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+	r := NewRecord(Focus, uuid.New(), start)
+
+	if err := s.Record(*r); err != nil {
+		t.Fatal(err)
+	}
+	r.StartedAt = start.Add(time.Minute)
+	r.Duration = 24 * time.Minute
+	if err := s.Record(*r); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record(*NewRecord(Short, r.TaskID, start.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := s.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history holds %d records, want 2", len(history))
+	}
+	if history[0].ID != r.ID || !history[0].StartedAt.Equal(r.StartedAt) || history[0].Duration != r.Duration {
+		t.Errorf("history starts with %+v, want the record as written last", history[0])
+	}
+}
+
+func TestIndexHistory(t *testing.T) {
+	s := newTestStore(t)
+	start := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+	r := NewRecord(Focus, uuid.New(), start)
+
+	// a record written before the index existed
+	err := s.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return txn.Set(r.Key(), data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexHistory(s.db.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	r.StartedAt = start.Add(time.Minute)
+	if err := s.Record(*r); err != nil {
+		t.Fatal(err)
+	}
+	history, err := s.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || !history[0].StartedAt.Equal(r.StartedAt) {
+		t.Errorf("history holds %+v, want only the rewritten record", history)
+	}
+}
@@ -0,0 +1,36 @@
+package sessions
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/dgraph-io/badger/v3"
+)
+
+var Migrations = []database.Migration{
+	{
+		Version:     1,
+		Description: "initialize session cycle record",
+		Up: func(db *badger.DB) error {
+			return db.Update(func(txn *badger.Txn) error {
+				_, err := txn.Get(Key)
+				if !errors.Is(err, badger.ErrKeyNotFound) {
+					return err
+				}
+
+				data, err := json.Marshal(&defaultSession)
+				if err != nil {
+					return err
+				}
+
+				return txn.Set(Key, data)
+			})
+		},
+	},
+	{
+		Version:     2,
+		Description: "index session history by record id",
+		Up:          indexHistory,
+	},
+}
@@ -2,13 +2,18 @@ package sessions
 
 import (
 	"encoding/json"
+	"time"
 
+	"github.com/aelnahas/pomo/database"
 	"github.com/dgraph-io/badger/v3"
 )
 
 type Session struct {
 	Current Type `json:"current"`
 	Count   int  `json:"count"`
+	// Break is the length a flow session earned for the break that follows
+	// it. Zero means the configured length.
+	Break time.Duration `json:"break,omitempty"`
 }
 
 func (s Session) Next(intervals int) Type {
@@ -23,7 +28,7 @@ func (s Session) Next(intervals int) Type {
 }
 
 type store struct {
-	db        *badger.DB
+	db        *database.DB
 	intervals int
 }
 
@@ -39,19 +44,17 @@ type Store interface {
 	Reset() error
 	Increment() error
 	Session() (*Session, error)
+	SetSession(session Session) error
+
+	Record(r Record) error
+	History() ([]Record, error)
 }
 
 var _ Store = &store{}
 
-func NewStore(path string, intervals int) (*store, error) {
-	opts := badger.DefaultOptions(path)
-	opts.Logger = nil
-	db, err := badger.Open(opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return &store{db: db, intervals: intervals}, nil
+// NewStoreOn keeps sessions in db, opened with the session Migrations.
+func NewStoreOn(db *database.DB, intervals int) *store {
+	return &store{db: db, intervals: intervals}
 }
 
 func (s *store) Reset() error {
@@ -110,6 +113,7 @@ func (s *store) Increment() error {
 		}
 
 		session.Current = session.Next(s.intervals)
+		session.Break = 0
 
 		data, err := json.Marshal(session)
 		if err != nil {
@@ -139,6 +143,17 @@ func (s *store) Session() (*Session, error) {
 	return session, nil
 }
 
+func (s *store) SetSession(session Session) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(&session)
+		if err != nil {
+			return err
+		}
+
+		return txn.Set(Key, data)
+	})
+}
+
 func (s *store) getCurrent(txn *badger.Txn) (*Session, error) {
 	item, err := txn.Get(Key)
 	if err != nil {
@@ -0,0 +1,199 @@
+package sound
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Sound string
+
+const (
+	FocusEnd Sound = "focus_end"
+	ShortEnd Sound = "short_end"
+	LongEnd  Sound = "long_end"
+	Tick     Sound = "tick"
+)
+
+const (
+	Auto = "auto"
+	Bell = "bell"
+	None = "none"
+)
+
+// commands are the players tried by Auto, in order.
+var commands = []string{"paplay", "aplay", "afplay"}
+
+const playTimeout = 10 * time.Second
+
+// Player plays a sound. Implementations may block until playback ends.
+type Player interface {
+	Play(s Sound) error
+}
+
+// Looper is a Player that can also play a sound over and over until ctx is
+// done, keeping one process going for many repeats rather than starting
+// one for each.
+type Looper interface {
+	Player
+	Loop(ctx context.Context, s Sound) error
+}
+
+// New returns the player for name: auto, bell, none or the name of a
+// command that plays a wav file given as its last argument. Files maps
+// sounds to wav files replacing the bundled ones.
+func New(name string, files map[Sound]string, bell io.Writer) (Player, error) {
+	switch name {
+	case "", Auto:
+		for _, command := range commands {
+			if path, err := exec.LookPath(command); err == nil {
+				return newCommand(path, files), nil
+			}
+		}
+		return &Terminal{out: bell}, nil
+	case Bell:
+		return &Terminal{out: bell}, nil
+	case None:
+		return Nop{}, nil
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("sound player %q not found (%w)", name, err)
+	}
+	return newCommand(path, files), nil
+}
+
+// Command plays sounds with an external program such as aplay.
+type Command struct {
+	path  string
+	args  []string
+	files map[Sound]string
+
+	// mu guards the bundled sounds written to dir, sounds are played from
+	// several goroutines.
+	mu      sync.Mutex
+	dir     string
+	written map[Sound]string
+}
+
+func newCommand(path string, files map[Sound]string) *Command {
+	c := &Command{path: path, files: files, written: make(map[Sound]string)}
+	if filepath.Base(path) == "aplay" {
+		c.args = []string{"-q"}
+	}
+	return c
+}
+
+func (c *Command) Play(s Sound) error {
+	file, err := c.file(s)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), playTimeout)
+	defer cancel()
+	return c.run(ctx, file)
+}
+
+// Loop plays s back to back until ctx is done. The bundled tick is looped
+// as a minute of ticks, so one process plays sixty of them, a configured
+// tick file is played as it is.
+func (c *Command) Loop(ctx context.Context, s Sound) error {
+	if s == Tick && c.files[Tick] == "" {
+		s = ticking
+	}
+
+	file, err := c.file(s)
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		if err := c.run(ctx, file); err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Command) run(ctx context.Context, file string) error {
+	args := append(append([]string{}, c.args...), file)
+	return exec.CommandContext(ctx, c.path, args...).Run()
+}
+
+// file returns the configured file for s, writing the bundled sound to a
+// temporary directory the first time it is needed.
+func (c *Command) file(s Sound) (string, error) {
+	if path := c.files[s]; path != "" {
+		return path, nil
+	}
+
+	tones, ok := bundled[s]
+	if !ok {
+		return "", fmt.Errorf("unknown sound %q", s)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if path, ok := c.written[s]; ok {
+		return path, nil
+	}
+
+	if c.dir == "" {
+		dir, err := os.MkdirTemp("", "pomo-sounds-")
+		if err != nil {
+			return "", err
+		}
+		c.dir = dir
+	}
+
+	path := filepath.Join(c.dir, string(s)+".wav")
+	if err := os.WriteFile(path, synthesize(tones), 0o600); err != nil {
+		return "", err
+	}
+	c.written[s] = path
+	return path, nil
+}
+
+// Close removes the bundled sounds written to disk.
+func (c *Command) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(c.dir)
+}
+
+// Terminal rings the terminal bell at the end of a session. It stays
+// quiet for ticks, a bell every second would be unbearable.
+type Terminal struct {
+	out io.Writer
+}
+
+func (t *Terminal) Play(s Sound) error {
+	if s == Tick {
+		return nil
+	}
+
+	out := t.out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err := fmt.Fprint(out, "\a")
+	return err
+}
+
+type Nop struct{}
+
+func (Nop) Play(s Sound) error {
+	return nil
+}
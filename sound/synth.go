@@ -0,0 +1,84 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+const sampleRate = 22050
+
+// tone is one note of a bundled sound, or a rest when it has no frequency.
+type tone struct {
+	frequency float64
+	duration  time.Duration
+	volume    float64
+}
+
+// bundled sounds are synthesized rather than shipped as files, so they
+// cost a few lines instead of a few hundred kilobytes in the binary.
+var bundled = map[Sound][]tone{
+	FocusEnd: {
+		{frequency: 880, duration: 180 * time.Millisecond, volume: 0.5},
+		{frequency: 1320, duration: 420 * time.Millisecond, volume: 0.5},
+	},
+	ShortEnd: {
+		{frequency: 660, duration: 150 * time.Millisecond, volume: 0.5},
+		{frequency: 880, duration: 150 * time.Millisecond, volume: 0.5},
+		{frequency: 1100, duration: 300 * time.Millisecond, volume: 0.5},
+	},
+	LongEnd: {
+		{frequency: 523, duration: 150 * time.Millisecond, volume: 0.5},
+		{frequency: 659, duration: 150 * time.Millisecond, volume: 0.5},
+		{frequency: 784, duration: 150 * time.Millisecond, volume: 0.5},
+		{frequency: 1047, duration: 400 * time.Millisecond, volume: 0.5},
+	},
+	Tick: {
+		{frequency: 2000, duration: 15 * time.Millisecond, volume: 0.2},
+	},
+}
+
+// ticking is a minute of ticks, one a second, for looping the tick with
+// one player process rather than one per second.
+const ticking Sound = "ticking"
+
+func init() {
+	tick := bundled[Tick]
+	second := append(append([]tone{}, tick...), tone{duration: time.Second - tick[0].duration})
+	for i := 0; i < 60; i++ {
+		bundled[ticking] = append(bundled[ticking], second...)
+	}
+}
+
+// synthesize renders tones as a 16-bit mono wav file. Each note decays
+// exponentially so consecutive notes do not click into each other.
+func synthesize(tones []tone) []byte {
+	var samples []int16
+	for _, t := range tones {
+		n := int(t.duration.Seconds() * sampleRate)
+		for i := 0; i < n; i++ {
+			at := float64(i) / sampleRate
+			envelope := math.Exp(-4 * float64(i) / float64(n))
+			v := t.volume * envelope * math.Sin(2*math.Pi*t.frequency*at)
+			samples = append(samples, int16(v*math.MaxInt16))
+		}
+	}
+
+	var buf bytes.Buffer
+	size := uint32(len(samples) * 2)
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+size)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, size)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
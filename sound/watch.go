@@ -0,0 +1,78 @@
+package sound
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+)
+
+// ending maps a session type to the sound played when it completes.
+var ending = map[sessions.Type]Sound{
+	sessions.Focus: FocusEnd,
+	sessions.Short: ShortEnd,
+	sessions.Long:  LongEnd,
+}
+
+// Watch plays sounds for events published on bus: a chime when a session
+// completes and, when ticking is set and the player can loop, ticks for as
+// long as a focus session runs. The returned function stops the ticking
+// and waits for sounds still playing.
+func Watch(bus *events.Bus, player Player, ticking bool) func() {
+	updates, unsubscribe := bus.Subscribe(16)
+	looper, loops := player.(Looper)
+	ticking = ticking && loops
+
+	var playing sync.WaitGroup
+	var stopTicking context.CancelFunc
+	startTicking := func() {
+		if stopTicking != nil {
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stopTicking = cancel
+		playing.Add(1)
+		go func() {
+			defer playing.Done()
+			looper.Loop(ctx, Tick)
+		}()
+	}
+	quiet := func() {
+		if stopTicking != nil {
+			stopTicking()
+			stopTicking = nil
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer quiet()
+		for e := range updates {
+			switch e.Type {
+			case events.Started, events.Resumed:
+				if ticking && e.Session == sessions.Focus {
+					startTicking()
+				}
+			case events.Paused, events.Interrupted, events.Skipped:
+				quiet()
+			case events.Completed:
+				quiet()
+				if s, ok := ending[e.Session]; ok {
+					playing.Add(1)
+					go func() {
+						defer playing.Done()
+						player.Play(s)
+					}()
+				}
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		<-done
+		playing.Wait()
+	}
+}
@@ -0,0 +1,82 @@
+package synclog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timestamp is a hybrid logical clock reading. Wall tracks physical time so
+// timestamps stay meaningful to people, Logical orders events that happen
+// within the same wall reading and Device breaks the remaining ties so every
+// replica sorts operations the same way.
+type Timestamp struct {
+	Wall    int64  `json:"wall"`
+	Logical uint32 `json:"logical"`
+	Device  string `json:"device"`
+}
+
+func (t Timestamp) Less(other Timestamp) bool {
+	switch {
+	case t.Wall != other.Wall:
+		return t.Wall < other.Wall
+	case t.Logical != other.Logical:
+		return t.Logical < other.Logical
+	default:
+		return t.Device < other.Device
+	}
+}
+
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%s.%d@%s", time.Unix(0, t.Wall).UTC().Format(time.RFC3339Nano), t.Logical, t.Device)
+}
+
+type Clock struct {
+	last   Timestamp
+	device string
+	now    func() time.Time
+}
+
+func NewClock(device string, last Timestamp) *Clock {
+	return &Clock{
+		last:   last,
+		device: device,
+		now:    time.Now,
+	}
+}
+
+func (c *Clock) Now() Timestamp {
+	wall := c.now().UnixNano()
+	if wall > c.last.Wall {
+		c.last = Timestamp{Wall: wall}
+	} else {
+		c.last.Logical++
+	}
+
+	c.last.Device = c.device
+	return c.last
+}
+
+// Observe moves the clock past a timestamp received from another device so
+// anything that happens locally afterwards sorts after it.
+func (c *Clock) Observe(remote Timestamp) {
+	wall := c.now().UnixNano()
+	switch {
+	case wall > c.last.Wall && wall > remote.Wall:
+		c.last = Timestamp{Wall: wall}
+	case c.last.Wall == remote.Wall:
+		if remote.Logical > c.last.Logical {
+			c.last.Logical = remote.Logical
+		}
+		c.last.Logical++
+	case c.last.Wall > remote.Wall:
+		c.last.Logical++
+	default:
+		c.last = Timestamp{Wall: remote.Wall, Logical: remote.Logical + 1}
+	}
+
+	c.last.Device = c.device
+}
+
+func (c *Clock) Last() Timestamp {
+	return c.last
+}
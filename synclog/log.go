@@ -0,0 +1,279 @@
+package synclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+type Kind string
+
+const (
+	Set         Kind = "set"
+	AddSessions Kind = "add-sessions"
+	Remove      Kind = "remove"
+)
+
+const logSuffix = ".jsonl"
+
+var (
+	clockKey        = []byte("sync/clock")
+	seqKey          = []byte("sync/seq")
+	seenPrefix      = "sync/seen/"
+	fieldPrefix     = "sync/field/"
+	tombstonePrefix = "sync/tombstone/"
+)
+
+// Op is a single change to a task as recorded in a device's log. Set carries
+// the new value of every changed field, keyed by its json name, and is merged
+// last-writer-wins per field. AddSessions carries a delta so pomodoros logged
+// on different devices add up instead of overwriting each other.
+type Op struct {
+	Device string                     `json:"device"`
+	Seq    uint64                     `json:"seq"`
+	At     Timestamp                  `json:"at"`
+	Kind   Kind                       `json:"kind"`
+	TaskID uuid.UUID                  `json:"task_id"`
+	Fields map[string]json.RawMessage `json:"fields,omitempty"`
+	Delta  int                        `json:"delta,omitempty"`
+}
+
+type Log struct {
+	db     *database.DB
+	dir    string
+	device string
+	clock  *Clock
+	seq    uint64
+}
+
+func Open(dir, device string, db *database.DB) (*Log, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	l := &Log{db: db, dir: dir, device: device}
+	err := db.View(func(txn *badger.Txn) error {
+		var last Timestamp
+		if err := getJSON(txn, clockKey, &last); err != nil {
+			return err
+		}
+		l.clock = NewClock(device, last)
+
+		seq, err := getUint(txn, seqKey)
+		l.seq = seq
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) Device() string {
+	return l.device
+}
+
+func (l *Log) path(device string) string {
+	return filepath.Join(l.dir, device+logSuffix)
+}
+
+// Append stamps op, writes it to this device's log and records the stamp
+// against every field it touches so older remote writes lose to it.
+func (l *Log) Append(op Op) error {
+	op.Device = l.device
+	op.Seq = l.seq + 1
+	op.At = l.clock.Now()
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path(l.device), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	l.seq = op.Seq
+	return l.db.Update(func(txn *badger.Txn) error {
+		if err := stamp(txn, op); err != nil {
+			return err
+		}
+		return l.saveClock(txn)
+	})
+}
+
+func (l *Log) saveClock(txn *badger.Txn) error {
+	if err := setJSON(txn, clockKey, l.clock.Last()); err != nil {
+		return err
+	}
+	return txn.Set(seqKey, []byte(formatUint(l.seq)))
+}
+
+// pending reads every other device's log and returns the operations this
+// replica has not applied yet, in timestamp order, along with the log lines
+// that could not be read.
+func (l *Log) pending() ([]Op, []string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ops := make([]Op, 0)
+	skipped := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, logSuffix) {
+			continue
+		}
+
+		device := strings.TrimSuffix(name, logSuffix)
+		if device == l.device {
+			continue
+		}
+
+		var seen uint64
+		err := l.db.View(func(txn *badger.Txn) (err error) {
+			seen, err = getUint(txn, []byte(seenPrefix+device))
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		deviceOps, malformed, err := readLog(l.path(device), seen)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, line := range malformed {
+			skipped = append(skipped, fmt.Sprintf("%s:%d", name, line))
+		}
+		ops = append(ops, deviceOps...)
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].At.Less(ops[j].At)
+	})
+	return ops, skipped, nil
+}
+
+// readLog returns the ops in the log at path after the given sequence
+// number, and the numbers of the lines among them that could not be read,
+// which are skipped. Unreadable lines before ops merged already were
+// reported then and are not again. A last line without a newline may still
+// be in flight from the other device and is left for the next read.
+func readLog(path string, after uint64) ([]Op, []int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	// the piece after the last newline is empty, or a line still being written
+	lines = lines[:len(lines)-1]
+
+	ops := make([]Op, 0)
+	malformed := make([]int, 0)
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var op Op
+		if err := json.Unmarshal(line, &op); err != nil {
+			malformed = append(malformed, i+1)
+			continue
+		}
+		if op.Seq > after {
+			ops = append(ops, op)
+		} else {
+			malformed = malformed[:0]
+		}
+	}
+
+	return ops, malformed, nil
+}
+
+func stamp(txn *badger.Txn, op Op) error {
+	switch op.Kind {
+	case Set:
+		for field := range op.Fields {
+			if err := setJSON(txn, fieldKey(op.TaskID, field), op.At); err != nil {
+				return err
+			}
+		}
+	case Remove:
+		return setJSON(txn, tombstoneKey(op.TaskID), op.At)
+	}
+	return nil
+}
+
+func fieldKey(id uuid.UUID, field string) []byte {
+	return []byte(fieldPrefix + id.String() + "/" + field)
+}
+
+func tombstoneKey(id uuid.UUID) []byte {
+	return []byte(tombstonePrefix + id.String())
+}
+
+func getJSON(txn *badger.Txn, key []byte, v interface{}) error {
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return item.Value(func(val []byte) error {
+		return json.Unmarshal(val, v)
+	})
+}
+
+func setJSON(txn *badger.Txn, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return txn.Set(key, data)
+}
+
+func getUint(txn *badger.Txn, key []byte) (uint64, error) {
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	err = item.Value(func(val []byte) (err error) {
+		n, err = strconv.ParseUint(string(val), 10, 64)
+		return err
+	})
+	return n, err
+}
+
+func formatUint(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
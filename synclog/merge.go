@@ -0,0 +1,168 @@
+package synclog
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+// Merge applies every operation other devices have logged since the last
+// merge, returning how many it applied and the log lines, as file:line, it
+// skipped because they could not be read. store must be the undecorated
+// store so merged changes are not logged again as local ones.
+func (l *Log) Merge(store task.Store) (int, []string, error) {
+	ops, skipped, err := l.pending()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, op := range ops {
+		l.clock.Observe(op.At)
+
+		if err := l.apply(store, op); err != nil {
+			return 0, skipped, err
+		}
+
+		err := l.db.Update(func(txn *badger.Txn) error {
+			if err := txn.Set([]byte(seenPrefix+op.Device), []byte(formatUint(op.Seq))); err != nil {
+				return err
+			}
+			return l.saveClock(txn)
+		})
+		if err != nil {
+			return 0, skipped, err
+		}
+	}
+
+	return len(ops), skipped, nil
+}
+
+func (l *Log) apply(store task.Store, op Op) error {
+	existing, err := store.GetTask(op.TaskID)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		existing = nil
+	} else if err != nil {
+		return err
+	}
+
+	switch op.Kind {
+	case Set:
+		return l.applySet(store, existing, op)
+	case AddSessions:
+		if existing == nil {
+			return nil
+		}
+		existing.Sessions += op.Delta
+		return store.Put(*existing)
+	case Remove:
+		if existing == nil {
+			return l.db.Update(func(txn *badger.Txn) error {
+				return stamp(txn, op)
+			})
+		}
+
+		newest, err := l.newestField(op.TaskID)
+		if err != nil {
+			return err
+		}
+		if op.At.Less(newest) {
+			// edited on another device after it was removed here, keep it
+			return nil
+		}
+
+		if err := store.Remove(op.TaskID); err != nil {
+			return err
+		}
+		return l.db.Update(func(txn *badger.Txn) error {
+			return stamp(txn, op)
+		})
+	}
+
+	return nil
+}
+
+func (l *Log) applySet(store task.Store, existing *task.Task, op Op) error {
+	fields := make(map[string]json.RawMessage)
+	if existing != nil {
+		data, err := json.Marshal(existing)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	err := l.db.Update(func(txn *badger.Txn) error {
+		if existing == nil {
+			var removed Timestamp
+			if err := getJSON(txn, tombstoneKey(op.TaskID), &removed); err != nil {
+				return err
+			}
+			if op.At.Less(removed) {
+				return nil
+			}
+		}
+
+		for field, value := range op.Fields {
+			var current Timestamp
+			if err := getJSON(txn, fieldKey(op.TaskID, field), &current); err != nil {
+				return err
+			}
+			if !current.Less(op.At) {
+				continue
+			}
+
+			fields[field] = value
+			if err := setJSON(txn, fieldKey(op.TaskID, field), op.At); err != nil {
+				return err
+			}
+			changed = true
+		}
+		return nil
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	var merged task.Task
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return err
+	}
+	merged.ID = op.TaskID
+	return store.Put(merged)
+}
+
+func (l *Log) newestField(id uuid.UUID) (Timestamp, error) {
+	var newest Timestamp
+	err := l.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(fieldPrefix + id.String() + "/")
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var ts Timestamp
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &ts)
+			})
+			if err != nil {
+				return err
+			}
+			if newest.Less(ts) {
+				newest = ts
+			}
+		}
+		return nil
+	})
+	return newest, err
+}
@@ -0,0 +1,56 @@
+package synclog
+
+import (
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+// Snapshot logs every task sync has never seen, as if it had just been
+// added, so tasks that existed before sync was turned on reach the other
+// devices too. Tasks that were logged or merged before carry field stamps
+// and are left alone, so running it again logs nothing twice. store must be
+// the undecorated store.
+func (l *Log) Snapshot(store task.Store) (int, error) {
+	tasks, err := store.List(func(t task.Task) bool {
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logged := 0
+	decorated := Wrap(store, l)
+	for i := range tasks {
+		seen, err := l.seen(tasks[i].ID)
+		if err != nil {
+			return logged, err
+		}
+		if seen {
+			continue
+		}
+
+		if err := decorated.logChange(nil, &tasks[i]); err != nil {
+			return logged, err
+		}
+		logged++
+	}
+	return logged, nil
+}
+
+// seen reports whether any change to the task was logged or merged.
+func (l *Log) seen(id uuid.UUID) (bool, error) {
+	seen := false
+	err := l.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(fieldPrefix + id.String() + "/")
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		it.Rewind()
+		seen = it.Valid()
+		return nil
+	})
+	return seen, err
+}
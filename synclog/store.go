@@ -0,0 +1,139 @@
+package synclog
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aelnahas/pomo/task"
+	"github.com/google/uuid"
+)
+
+// Store decorates a task.Store, logging every change it makes so other
+// devices can replay it. Which task is current stays local to each device.
+type Store struct {
+	task.Store
+	log *Log
+}
+
+var _ task.Store = &Store{}
+
+func Wrap(store task.Store, log *Log) *Store {
+	return &Store{Store: store, log: log}
+}
+
+func (s *Store) Add(title string) (*task.Task, error) {
+	t, err := s.Store.Add(title)
+	if err != nil {
+		return nil, err
+	}
+	return t, s.logChange(nil, t)
+}
+
+func (s *Store) Remove(id uuid.UUID) error {
+	if err := s.Store.Remove(id); err != nil {
+		return err
+	}
+	return s.log.Append(Op{Kind: Remove, TaskID: id})
+}
+
+func (s *Store) SetState(id uuid.UUID, status task.Status) (*task.Task, error) {
+	return s.update(id, func() (*task.Task, error) {
+		return s.Store.SetState(id, status)
+	})
+}
+
+func (s *Store) AddSessions(id uuid.UUID) (*task.Task, error) {
+	return s.update(id, func() (*task.Task, error) {
+		return s.Store.AddSessions(id)
+	})
+}
+
+func (s *Store) Credit(id uuid.UUID, pomodoros float64) (*task.Task, error) {
+	return s.update(id, func() (*task.Task, error) {
+		return s.Store.Credit(id, pomodoros)
+	})
+}
+
+func (s *Store) Put(t task.Task) error {
+	before, err := s.Store.GetTask(t.ID)
+	if err != nil {
+		before = nil
+	}
+
+	if err := s.Store.Put(t); err != nil {
+		return err
+	}
+	return s.logChange(before, &t)
+}
+
+func (s *Store) update(id uuid.UUID, fn func() (*task.Task, error)) (*task.Task, error) {
+	before, err := s.Store.GetTask(id)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	return after, s.logChange(before, after)
+}
+
+// logChange appends a set op for every field that differs between before and
+// after, and an add-sessions op for any change in the pomodoro count.
+func (s *Store) logChange(before, after *task.Task) error {
+	old, err := fieldsOf(before)
+	if err != nil {
+		return err
+	}
+	updated, err := fieldsOf(after)
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for field, value := range updated {
+		if !bytes.Equal(old[field], value) {
+			changed[field] = value
+		}
+	}
+	for field := range old {
+		if _, ok := updated[field]; !ok {
+			changed[field] = json.RawMessage("null")
+		}
+	}
+
+	if len(changed) > 0 {
+		if err := s.log.Append(Op{Kind: Set, TaskID: after.ID, Fields: changed}); err != nil {
+			return err
+		}
+	}
+
+	delta := after.Sessions
+	if before != nil {
+		delta -= before.Sessions
+	}
+	if delta != 0 {
+		return s.log.Append(Op{Kind: AddSessions, TaskID: after.ID, Delta: delta})
+	}
+	return nil
+}
+
+func fieldsOf(t *task.Task) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if t == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	delete(fields, "id")
+	delete(fields, "sessions")
+	return fields, nil
+}
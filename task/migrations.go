@@ -0,0 +1,78 @@
+package task
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aelnahas/pomo/database"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+var Migrations = []database.Migration{
+	{
+		Version:     1,
+		Description: "normalize task status and creation time",
+		Up: func(db *badger.DB) error {
+			return rewriteTasks(db, func(t *Task) {
+				if t.Status == "" {
+					t.Status = Pending
+				}
+				if t.CreatedAT.IsZero() {
+					t.CreatedAT = time.Now()
+				}
+			})
+		},
+	},
+}
+
+// rewriteTasks applies update to every task. The rewritten tasks go
+// through a write batch, which commits as it fills, so large databases do
+// not outgrow a single transaction.
+func rewriteTasks(db *badger.DB, update func(t *Task)) error {
+	tasks := make([]Task, 0)
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if _, err := uuid.ParseBytes(item.Key()); err != nil {
+				continue
+			}
+
+			err := item.Value(func(val []byte) error {
+				var t Task
+				if err := json.Unmarshal(val, &t); err != nil {
+					return err
+				}
+				tasks = append(tasks, t)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for i := range tasks {
+		update(&tasks[i])
+		data, err := json.Marshal(tasks[i])
+		if err != nil {
+			return err
+		}
+
+		if err := batch.Set(tasks[i].Key(), data); err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
@@ -3,9 +3,11 @@ package task
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/aelnahas/pomo/database"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/google/uuid"
 )
@@ -23,12 +25,25 @@ var (
 )
 
 type Task struct {
-	ID        uuid.UUID  `json:"id"`
-	Title     string     `json:"title"`
-	Status    Status     `json:"status"`
-	Sessions  int        `json:"sessions"`
-	CreatedAT time.Time  `json:"created_at"`
-	UpdatedAT *time.Time `json:"updated_at"`
+	ID          uuid.UUID    `json:"id"`
+	Title       string       `json:"title"`
+	Status      Status       `json:"status"`
+	Sessions    int          `json:"sessions"`
+	Partial     float64      `json:"partial,omitempty"`
+	CreatedAT   time.Time    `json:"created_at"`
+	UpdatedAT   *time.Time   `json:"updated_at"`
+	Priority    string       `json:"priority,omitempty"`
+	Projects    []string     `json:"projects,omitempty"`
+	Contexts    []string     `json:"contexts,omitempty"`
+	Due         *time.Time   `json:"due,omitempty"`
+	CompletedAT *time.Time   `json:"completed_at,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+type Annotation struct {
+	Entry       time.Time `json:"entry"`
+	Description string    `json:"description"`
 }
 
 func NewTask(title string) *Task {
@@ -61,6 +76,9 @@ type Store interface {
 	List(filter FilterTask) ([]Task, error)
 	SetState(id uuid.UUID, status Status) (*Task, error)
 	AddSessions(id uuid.UUID) (*Task, error)
+	Credit(id uuid.UUID, pomodoros float64) (*Task, error)
+	GetTask(id uuid.UUID) (*Task, error)
+	Put(t Task) error
 
 	ClearCurrentTask(id uuid.UUID) error
 	SetCurrentTask(id uuid.UUID) error
@@ -68,25 +86,16 @@ type Store interface {
 }
 
 type store struct {
-	db *badger.DB
+	db *database.DB
 }
 
 var _ Store = &store{}
 
-func NewStore(path string) (*store, error) {
-	opts := badger.DefaultOptions(path)
-	opts.Logger = nil
-	db, err := badger.Open(opts)
-	if err != nil {
-		return nil, err
-	}
+// NewStoreOn keeps tasks in db, opened with the task Migrations.
+func NewStoreOn(db *database.DB) *store {
 	return &store{
 		db: db,
-	}, nil
-}
-
-func (s *store) Close() error {
-	return s.db.Close()
+	}
 }
 
 func (s *store) Add(title string) (*Task, error) {
@@ -175,7 +184,13 @@ func (s *store) SetState(id uuid.UUID, status Status) (task *Task, err error) {
 			return err
 		}
 
+		now := time.Now()
 		task.Status = status
+		task.UpdatedAT = &now
+		task.CompletedAT = nil
+		if status == Complete {
+			task.CompletedAT = &now
+		}
 		data, err := json.Marshal(task)
 		if err != nil {
 			return err
@@ -202,7 +217,44 @@ func (s *store) AddSessions(id uuid.UUID) (*Task, error) {
 			return err
 		}
 
+		now := time.Now()
 		task.Sessions++
+		task.UpdatedAT = &now
+		data, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+
+		return txn.Set(byteID, data)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Credit adds a fractional number of pomodoros to a task. Whole ones go to
+// Sessions and the rest is kept in Partial until it adds up to another.
+func (s *store) Credit(id uuid.UUID, pomodoros float64) (*Task, error) {
+	var task *Task
+	byteID := []byte(id.String())
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		var err error
+		task, err = s.getTaskByID(id, txn)
+		if err != nil {
+			return err
+		}
+
+		total := task.Partial + pomodoros
+		// the epsilon stops 0.7+0.3 from falling just short of a whole one
+		whole := math.Floor(total + 1e-9)
+		now := time.Now()
+		task.Sessions += int(whole)
+		task.Partial = math.Max(0, total-whole)
+		task.UpdatedAT = &now
 		data, err := json.Marshal(task)
 		if err != nil {
 			return err
@@ -218,6 +270,17 @@ func (s *store) AddSessions(id uuid.UUID) (*Task, error) {
 	return task, nil
 }
 
+func (s *store) Put(t Task) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+
+		return txn.Set(t.Key(), data)
+	})
+}
+
 func (s *store) GetTask(id uuid.UUID) (*Task, error) {
 	var task *Task
 	err := s.db.View(func(txn *badger.Txn) (err error) {
@@ -0,0 +1,224 @@
+package taskwarrior
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aelnahas/pomo/task"
+	"github.com/google/uuid"
+)
+
+const dateLayout = "20060102T150405Z"
+
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusDeleted   = "deleted"
+	StatusWaiting   = "waiting"
+	StatusRecurring = "recurring"
+)
+
+var priorities = map[string]string{
+	"H": "A",
+	"M": "B",
+	"L": "C",
+}
+
+type Annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+type Task struct {
+	UUID        string       `json:"uuid"`
+	Description string       `json:"description"`
+	Status      string       `json:"status"`
+	Entry       string       `json:"entry,omitempty"`
+	Modified    string       `json:"modified,omitempty"`
+	End         string       `json:"end,omitempty"`
+	Due         string       `json:"due,omitempty"`
+	Project     string       `json:"project,omitempty"`
+	Priority    string       `json:"priority,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+	Pomodoros   int          `json:"pomodoros,omitempty"`
+}
+
+// Read accepts both the JSON array written by `task export` and the
+// one-object-per-line output of older Taskwarrior releases.
+func Read(r io.Reader) ([]Task, error) {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+
+	tasks := make([]Task, 0)
+	if first, err := peekNonSpace(reader); err != nil {
+		return nil, err
+	} else if first == '[' {
+		if err := decoder.Decode(&tasks); err != nil {
+			return nil, err
+		}
+		return tasks, nil
+	}
+
+	for {
+		var t Task
+		err := decoder.Decode(&t)
+		if err == io.EOF {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+}
+
+func Write(w io.Writer, tasks ...Task) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tasks)
+}
+
+func ToTask(tw Task) (*task.Task, error) {
+	id, err := uuid.Parse(tw.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uuid %q (%w)", tw.UUID, err)
+	}
+
+	t := &task.Task{
+		ID:        id,
+		Title:     tw.Description,
+		Status:    task.Pending,
+		Sessions:  tw.Pomodoros,
+		CreatedAT: time.Now(),
+		Tags:      tw.Tags,
+	}
+
+	if tw.Status == StatusCompleted {
+		t.Status = task.Complete
+	}
+
+	if tw.Project != "" {
+		t.Projects = []string{tw.Project}
+	}
+
+	if p, ok := priorities[tw.Priority]; ok {
+		t.Priority = p
+	}
+
+	if tw.Entry != "" {
+		if t.CreatedAT, err = parseDate(tw.Entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.UpdatedAT, err = parseOptionalDate(tw.Modified); err != nil {
+		return nil, err
+	}
+	if t.CompletedAT, err = parseOptionalDate(tw.End); err != nil {
+		return nil, err
+	}
+	if t.Due, err = parseOptionalDate(tw.Due); err != nil {
+		return nil, err
+	}
+
+	for _, a := range tw.Annotations {
+		entry, err := parseDate(a.Entry)
+		if err != nil {
+			return nil, err
+		}
+		t.Annotations = append(t.Annotations, task.Annotation{Entry: entry, Description: a.Description})
+	}
+
+	return t, nil
+}
+
+func FromTask(t task.Task) Task {
+	tw := Task{
+		UUID:        t.ID.String(),
+		Description: t.Title,
+		Status:      StatusPending,
+		Entry:       formatDate(t.CreatedAT),
+		Tags:        t.Tags,
+		Pomodoros:   t.Sessions,
+	}
+
+	if t.Status == task.Complete {
+		tw.Status = StatusCompleted
+		tw.End = tw.Entry
+		if t.CompletedAT != nil {
+			tw.End = formatDate(*t.CompletedAT)
+		}
+	}
+
+	if len(t.Projects) > 0 {
+		tw.Project = t.Projects[0]
+	}
+
+	for tp, p := range priorities {
+		if p == t.Priority {
+			tw.Priority = tp
+		}
+	}
+
+	if t.UpdatedAT != nil {
+		tw.Modified = formatDate(*t.UpdatedAT)
+	}
+	if t.Due != nil {
+		tw.Due = formatDate(*t.Due)
+	}
+
+	for _, a := range t.Annotations {
+		tw.Annotations = append(tw.Annotations, Annotation{Entry: formatDate(a.Entry), Description: a.Description})
+	}
+
+	return tw
+}
+
+func parseDate(s string) (time.Time, error) {
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid taskwarrior date %q (%w)", s, err)
+	}
+	return d, nil
+}
+
+func parseOptionalDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	d, err := parseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format(dateLayout)
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := r.ReadByte(); err != nil {
+				return 0, err
+			}
+		default:
+			return b[0], nil
+		}
+	}
+}
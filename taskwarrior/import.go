@@ -0,0 +1,49 @@
+package taskwarrior
+
+import (
+	"errors"
+
+	"github.com/aelnahas/pomo/archive"
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+)
+
+type Summary struct {
+	Added   int
+	Updated int
+	Skipped int
+}
+
+func Import(store task.Store, tasks ...Task) (*Summary, error) {
+	summary := &Summary{}
+	for _, tw := range tasks {
+		if tw.Status == StatusDeleted {
+			summary.Skipped++
+			continue
+		}
+
+		incoming, err := ToTask(tw)
+		if err != nil {
+			return nil, err
+		}
+
+		existing, err := store.GetTask(incoming.ID)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			if err := store.Put(*incoming); err != nil {
+				return nil, err
+			}
+			summary.Added++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := store.Put(archive.MergeTasks(*existing, *incoming)); err != nil {
+			return nil, err
+		}
+		summary.Updated++
+	}
+
+	return summary, nil
+}
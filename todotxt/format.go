@@ -0,0 +1,212 @@
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/task"
+	"github.com/google/uuid"
+)
+
+const dateLayout = "2006-01-02"
+
+const (
+	keyDue      = "due"
+	keyPomo     = "pomo"
+	keyUUID     = "uuid"
+	keyPriority = "pri"
+)
+
+var (
+	priorityPattern = regexp.MustCompile(`^\(([A-Z])\)$`)
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	keyValuePattern = regexp.MustCompile(`^([^:\s]+):([^:\s]+)$`)
+)
+
+func Parse(line string) (*task.Task, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty todo.txt line")
+	}
+
+	t := &task.Task{
+		ID:        uuid.New(),
+		Status:    task.Pending,
+		CreatedAT: time.Now(),
+	}
+
+	if fields[0] == "x" {
+		t.Status = task.Complete
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		if m := priorityPattern.FindStringSubmatch(fields[0]); m != nil {
+			t.Priority = m[1]
+			fields = fields[1:]
+		}
+	}
+
+	dates := make([]time.Time, 0, 2)
+	for len(fields) > 0 && len(dates) < 2 && datePattern.MatchString(fields[0]) {
+		d, err := time.ParseInLocation(dateLayout, fields[0], time.Local)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, d)
+		fields = fields[1:]
+	}
+
+	switch {
+	case t.Status == task.Complete && len(dates) == 2:
+		t.CompletedAT = &dates[0]
+		t.CreatedAT = dates[1]
+	case t.Status == task.Complete && len(dates) == 1:
+		t.CompletedAT = &dates[0]
+	case len(dates) == 1:
+		t.CreatedAT = dates[0]
+	case len(dates) == 2:
+		// a pending task only carries a creation date, keep the second one in the title
+		t.CreatedAT = dates[0]
+		fields = append([]string{dates[1].Format(dateLayout)}, fields...)
+	}
+
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch {
+		case len(field) > 1 && strings.HasPrefix(field, "+"):
+			t.Projects = append(t.Projects, field[1:])
+		case len(field) > 1 && strings.HasPrefix(field, "@"):
+			t.Contexts = append(t.Contexts, field[1:])
+		case keyValuePattern.MatchString(field) && known(keyValuePattern.FindStringSubmatch(field)[1]):
+			m := keyValuePattern.FindStringSubmatch(field)
+			if err := setKeyValue(t, m[1], m[2]); err != nil {
+				return nil, err
+			}
+		default:
+			words = append(words, field)
+		}
+	}
+
+	t.Title = strings.Join(words, " ")
+	return t, nil
+}
+
+// known reports whether key is one pomo reads. Any other key:value, a time
+// like 10:30 or words like re:issue, stays in the title where it was written
+// so the line survives a round trip.
+func known(key string) bool {
+	switch key {
+	case keyDue, keyPomo, keyUUID, keyPriority:
+		return true
+	}
+	return false
+}
+
+func setKeyValue(t *task.Task, key, value string) error {
+	switch key {
+	case keyDue:
+		d, err := time.ParseInLocation(dateLayout, value, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid due date %q (%w)", value, err)
+		}
+		t.Due = &d
+	case keyPomo:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid pomodoro count %q (%w)", value, err)
+		}
+		t.Sessions = n
+	case keyUUID:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return err
+		}
+		t.ID = id
+	case keyPriority:
+		if t.Priority == "" {
+			t.Priority = value
+		}
+	}
+	return nil
+}
+
+func Format(t task.Task) string {
+	parts := make([]string, 0)
+
+	if t.Status == task.Complete {
+		parts = append(parts, "x")
+		switch {
+		case t.CompletedAT != nil:
+			parts = append(parts, t.CompletedAT.Format(dateLayout))
+		case t.UpdatedAT != nil:
+			parts = append(parts, t.UpdatedAT.Format(dateLayout))
+		case !t.CreatedAT.IsZero():
+			parts = append(parts, t.CreatedAT.Format(dateLayout))
+		}
+	} else if t.Priority != "" {
+		parts = append(parts, fmt.Sprintf("(%s)", t.Priority))
+	}
+
+	if !t.CreatedAT.IsZero() {
+		parts = append(parts, t.CreatedAT.Format(dateLayout))
+	}
+
+	parts = append(parts, t.Title)
+	for _, project := range t.Projects {
+		parts = append(parts, "+"+project)
+	}
+	for _, context := range t.Contexts {
+		parts = append(parts, "@"+context)
+	}
+
+	if t.Status == task.Complete && t.Priority != "" {
+		parts = append(parts, fmt.Sprintf("%s:%s", keyPriority, t.Priority))
+	}
+	if t.Due != nil {
+		parts = append(parts, fmt.Sprintf("%s:%s", keyDue, t.Due.Format(dateLayout)))
+	}
+
+	parts = append(parts, fmt.Sprintf("%s:%d", keyPomo, t.Sessions))
+	parts = append(parts, fmt.Sprintf("%s:%s", keyUUID, t.ID))
+	return strings.Join(parts, " ")
+}
+
+func Read(r io.Reader) ([]task.Task, error) {
+	tasks := make([]task.Task, 0)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		t, err := Parse(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		tasks = append(tasks, *t)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func Write(w io.Writer, tasks ...task.Task) error {
+	writer := bufio.NewWriter(w)
+	for _, t := range tasks {
+		if _, err := fmt.Fprintln(writer, Format(t)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,234 @@
+package todotxt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aelnahas/pomo/task"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/uuid"
+)
+
+type Summary struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Removed   int
+	Written   int
+}
+
+func Import(store task.Store, tasks ...task.Task) (*Summary, error) {
+	summary := &Summary{}
+	for _, incoming := range tasks {
+		existing, err := store.GetTask(incoming.ID)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			if err := store.Put(incoming); err != nil {
+				return nil, err
+			}
+			summary.Added++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		merged := merge(*existing, incoming)
+		if Format(merged) == Format(*existing) {
+			summary.Unchanged++
+			continue
+		}
+
+		now := time.Now()
+		merged.UpdatedAT = &now
+		if err := store.Put(merged); err != nil {
+			return nil, err
+		}
+		summary.Updated++
+	}
+
+	return summary, nil
+}
+
+// Sync merges the todo.txt file at path into the store and rewrites the file
+// with every task the store knows about. The file is authoritative for the
+// fields a person edits by hand, pomo is authoritative for pomodoro counts.
+// Tasks synced last time that have since been deleted from the file are
+// removed from the store, and ones removed from the store are dropped from
+// the file.
+func Sync(store task.Store, path string) (*Summary, error) {
+	fileTasks := make([]task.Task, 0)
+	exists := true
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		fileTasks, err = Read(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		exists = false
+	default:
+		return nil, err
+	}
+
+	// with the file gone there is nothing to tell a deletion from a fresh start
+	synced := make(map[uuid.UUID]bool)
+	if exists {
+		synced, err = readSynced(syncedPath(path))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inFile := make(map[uuid.UUID]bool, len(fileTasks))
+	kept := make([]task.Task, 0, len(fileTasks))
+	for _, t := range fileTasks {
+		inFile[t.ID] = true
+		if synced[t.ID] {
+			_, err := store.GetTask(t.ID)
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		kept = append(kept, t)
+	}
+	fileTasks = kept
+
+	removed := 0
+	for id := range synced {
+		if inFile[id] {
+			continue
+		}
+		err := store.Remove(id)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		removed++
+	}
+
+	summary, err := Import(store, fileTasks...)
+	if err != nil {
+		return nil, err
+	}
+	summary.Removed = removed
+
+	all, err := store.List(func(t task.Task) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]task.Task, len(all))
+	for _, t := range all {
+		byID[t.ID] = t
+	}
+
+	ordered := make([]task.Task, 0, len(all))
+	for _, t := range fileTasks {
+		if stored, ok := byID[t.ID]; ok {
+			ordered = append(ordered, stored)
+			delete(byID, t.ID)
+		}
+	}
+	for _, t := range all {
+		if _, ok := byID[t.ID]; ok {
+			ordered = append(ordered, t)
+		}
+	}
+
+	if err := writeFile(path, ordered...); err != nil {
+		return nil, err
+	}
+	if err := writeSynced(syncedPath(path), ordered...); err != nil {
+		return nil, err
+	}
+
+	summary.Written = len(ordered)
+	return summary, nil
+}
+
+// syncedPath is where Sync keeps the ids it last wrote to the file at path.
+func syncedPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".synced")
+}
+
+func readSynced(path string) (map[uuid.UUID]bool, error) {
+	synced := make(map[uuid.UUID]bool)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return synced, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if id, err := uuid.Parse(strings.TrimSpace(line)); err == nil {
+			synced[id] = true
+		}
+	}
+	return synced, nil
+}
+
+func writeSynced(path string, tasks ...task.Task) error {
+	var b strings.Builder
+	for _, t := range tasks {
+		b.WriteString(t.ID.String())
+		b.WriteString("\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+func merge(local, incoming task.Task) task.Task {
+	merged := local
+	merged.Title = incoming.Title
+	merged.Priority = incoming.Priority
+	merged.Projects = incoming.Projects
+	merged.Contexts = incoming.Contexts
+	merged.Due = incoming.Due
+
+	if incoming.Status == task.Complete && local.Status != task.Complete {
+		merged.Status = task.Complete
+		merged.CompletedAT = incoming.CompletedAT
+	}
+
+	if incoming.Sessions > merged.Sessions {
+		merged.Sessions = incoming.Sessions
+	}
+
+	return merged
+}
+
+func writeFile(path string, tasks ...task.Task) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".todo.txt.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, tasks...); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
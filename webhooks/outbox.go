@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const failedDir = "failed"
+
+// Delivery is one event queued for one target. The body is kept verbatim so
+// every attempt is signed over the same bytes.
+type Delivery struct {
+	ID          string          `json:"id"`
+	URL         string          `json:"url"`
+	Event       string          `json:"event"`
+	Body        json.RawMessage `json:"body"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+func newDelivery(url, event string, body []byte, at time.Time) Delivery {
+	return Delivery{
+		ID:          fmt.Sprintf("%020d-%s", at.UnixNano(), uuid.New()),
+		URL:         url,
+		Event:       event,
+		Body:        body,
+		CreatedAt:   at,
+		NextAttempt: at,
+	}
+}
+
+// Outbox persists pending deliveries as one json file each, so events
+// survive restarts and a receiver that is down. Deliveries that run out of
+// attempts, and files that cannot be read back, are moved to the failed
+// subdirectory. The directories are only created once something is queued.
+type Outbox struct {
+	dir string
+}
+
+func NewOutbox(dir string) *Outbox {
+	return &Outbox{dir: dir}
+}
+
+func (o *Outbox) Dir() string {
+	return o.dir
+}
+
+// FailedDir is where deliveries that gave up, and unreadable files, go.
+func (o *Outbox) FailedDir() string {
+	return filepath.Join(o.dir, failedDir)
+}
+
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.dir, id+".json")
+}
+
+func (o *Outbox) Put(d Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(o.dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(o.dir, ".delivery-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), o.path(d.ID))
+}
+
+// List returns pending deliveries, oldest first. Files that are not
+// deliveries are moved to the failed directory and their names returned
+// as unreadable, so one bad file does not hold up the rest.
+func (o *Outbox) List() (deliveries []Delivery, unreadable []string, err error) {
+	entries, err := os.ReadDir(o.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deliveries = make([]Delivery, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(o.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			// sent by another process in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var d Delivery
+		if err := json.Unmarshal(data, &d); err != nil || d.ID == "" {
+			if err := o.quarantine(name); err != nil {
+				return nil, nil, err
+			}
+			unreadable = append(unreadable, name)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, unreadable, nil
+}
+
+// quarantine moves a file that is not a delivery to the failed directory.
+func (o *Outbox) quarantine(name string) error {
+	if err := os.MkdirAll(o.FailedDir(), 0o700); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(o.dir, name), filepath.Join(o.FailedDir(), name))
+}
+
+func (o *Outbox) Remove(id string) error {
+	err := os.Remove(o.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Fail moves a delivery out of the queue into the failed directory.
+func (o *Outbox) Fail(d Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(o.FailedDir(), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(o.FailedDir(), d.ID+".json"), data, 0o600); err != nil {
+		return err
+	}
+	return o.Remove(d.ID)
+}
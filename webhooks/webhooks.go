@@ -0,0 +1,353 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+)
+
+const (
+	SessionStarted     = "session.started"
+	SessionCompleted   = "session.completed"
+	SessionInterrupted = "session.interrupted"
+	TaskAdded          = "task.added"
+	TaskCompleted      = "task.completed"
+)
+
+const (
+	SignatureHeader = "X-Pomo-Signature"
+	EventHeader     = "X-Pomo-Event"
+	DeliveryHeader  = "X-Pomo-Delivery"
+
+	DefaultTimeout     = 5 * time.Second
+	DefaultMaxAttempts = 10
+
+	maxBackoff = 10 * time.Minute
+)
+
+var names = map[events.Type]string{
+	events.Started:       SessionStarted,
+	events.Completed:     SessionCompleted,
+	events.Interrupted:   SessionInterrupted,
+	events.TaskAdded:     TaskAdded,
+	events.TaskCompleted: TaskCompleted,
+}
+
+// Target is a receiver of webhooks. An empty Events list subscribes it to
+// every event.
+type Target struct {
+	URL    string
+	Secret string
+	Events []string
+}
+
+func (t Target) wants(event string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the json body posted to targets.
+type Payload struct {
+	ID        string       `json:"id"`
+	Event     string       `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Data      events.Event `json:"data"`
+}
+
+// Sign returns the signature header value for body: the hex encoded
+// HMAC-SHA256 of the body keyed with the target's secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature matches body, for use by receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Sender queues events in the outbox and delivers them, retrying failed
+// deliveries with exponential backoff until MaxAttempts is reached.
+type Sender struct {
+	mu          sync.Mutex
+	targets     []Target
+	outbox      *Outbox
+	client      *http.Client
+	maxAttempts int
+	// wake tells Deliver something was queued.
+	wake chan struct{}
+}
+
+func NewSender(targets []Target, outbox *Outbox, timeout time.Duration, maxAttempts int) *Sender {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	return &Sender{
+		targets:     targets,
+		outbox:      outbox,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+func (s *Sender) Enabled() bool {
+	return len(s.targets) > 0
+}
+
+func (s *Sender) Outbox() *Outbox {
+	return s.outbox
+}
+
+// Enqueue stores a delivery for every target interested in e. Events
+// without a webhook name are ignored.
+func (s *Sender) Enqueue(e events.Event) error {
+	event, ok := names[e.Type]
+	if !ok {
+		return nil
+	}
+
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	for _, target := range s.targets {
+		if !target.wants(event) {
+			continue
+		}
+
+		d := newDelivery(target.URL, event, nil, e.At)
+		body, err := json.Marshal(Payload{ID: d.ID, Event: event, CreatedAt: e.At, Data: e})
+		if err != nil {
+			return err
+		}
+		d.Body = body
+
+		if err := s.outbox.Put(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Result summarizes a flush of the outbox.
+type Result struct {
+	Sent    int
+	Retried int
+	Failed  int
+	Pending int
+	Next    time.Time
+	// Unreadable names the files moved to the failed directory because
+	// they could not be read back as deliveries.
+	Unreadable []string
+}
+
+// Flush attempts every delivery that is due, or every delivery when force
+// is set.
+func (s *Sender) Flush(force bool) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result Result
+	deliveries, unreadable, err := s.outbox.List()
+	result.Unreadable = unreadable
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	for _, d := range deliveries {
+		if !force && d.NextAttempt.After(now) {
+			result.Pending++
+			result.Next = earliest(result.Next, d.NextAttempt)
+			continue
+		}
+
+		target, ok := s.target(d.URL)
+		if !ok {
+			d.LastError = "target is no longer configured"
+			if err := s.outbox.Fail(d); err != nil {
+				return result, err
+			}
+			result.Failed++
+			continue
+		}
+
+		err := s.send(target, d)
+		if err == nil {
+			if err := s.outbox.Remove(d.ID); err != nil {
+				return result, err
+			}
+			result.Sent++
+			continue
+		}
+
+		d.Attempts++
+		d.LastError = err.Error()
+		if d.Attempts >= s.maxAttempts {
+			if err := s.outbox.Fail(d); err != nil {
+				return result, err
+			}
+			result.Failed++
+			continue
+		}
+
+		d.NextAttempt = time.Now().Add(backoff(d.Attempts))
+		if err := s.outbox.Put(d); err != nil {
+			return result, err
+		}
+		result.Retried++
+		result.Pending++
+		result.Next = earliest(result.Next, d.NextAttempt)
+	}
+
+	return result, nil
+}
+
+func (s *Sender) target(url string) (Target, bool) {
+	for _, t := range s.targets {
+		if t.URL == url {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+func (s *Sender) send(target Target, d Delivery) error {
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pomo-webhook")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, d.ID)
+	if target.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(target.Secret, d.Body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded %s", target.URL, res.Status)
+	}
+	return nil
+}
+
+// Watch queues events published on bus in the outbox, to be delivered by
+// Deliver, here or in a later run. The returned function stops watching
+// once everything published so far is queued.
+func (s *Sender) Watch(bus *events.Bus) func() {
+	updates, unsubscribe := bus.Subscribe(64)
+	queued := make(chan struct{})
+
+	go func() {
+		defer close(queued)
+		for e := range updates {
+			if _, ok := names[e.Type]; !ok {
+				continue
+			}
+			if err := s.Enqueue(e); err == nil {
+				s.wakeUp()
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		<-queued
+	}
+}
+
+// Deliver sends deliveries in the background as they are queued or fall
+// due, starting with whatever earlier runs left in the outbox. Only
+// commands that keep running for a while deliver, so short ones never wait
+// on a receiver. The returned function stops after a last attempt at
+// anything due; the rest stays in the outbox for the next run.
+func (s *Sender) Deliver() func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		retry := time.NewTimer(0)
+		defer retry.Stop()
+
+		for {
+			select {
+			case <-stop:
+				s.Flush(false)
+				return
+			case <-s.wake:
+			case <-retry.C:
+			}
+
+			result, err := s.Flush(false)
+			if err != nil || result.Next.IsZero() {
+				continue
+			}
+
+			if !retry.Stop() {
+				select {
+				case <-retry.C:
+				default:
+				}
+			}
+			retry.Reset(time.Until(result.Next))
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func (s *Sender) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	d := time.Second << uint(attempts-1)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,397 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aelnahas/pomo/events"
+	"github.com/aelnahas/pomo/sessions"
+	"github.com/aelnahas/pomo/task"
+)
+
+// receiver stands in for a webhook target, answering with the statuses in
+// replies in turn and 200 once they run out.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	replies  []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, replies ...int) *receiver {
+	r := &receiver{replies: replies}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("reading body: %s", err)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+
+		status := http.StatusOK
+		if len(r.replies) > 0 {
+			status, r.replies = r.replies[0], r.replies[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func (r *receiver) request(i int) (*http.Request, []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[i], r.bodies[i]
+}
+
+func newTestSender(t *testing.T, dir string, maxAttempts int, targets ...Target) *Sender {
+	return NewSender(targets, NewOutbox(dir), time.Second, maxAttempts)
+}
+
+func completed() events.Event {
+	return events.Event{
+		Type:    events.Completed,
+		At:      time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC),
+		Session: sessions.Focus,
+		Task:    &task.Task{Title: "write tests"},
+	}
+}
+
+func pending(t *testing.T, s *Sender) []Delivery {
+	deliveries, unreadable, err := s.Outbox().List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unreadable) != 0 {
+		t.Errorf("unreadable deliveries %v", unreadable)
+	}
+	return deliveries
+}
+
+func TestFlushSignsDeliveries(t *testing.T) {
+	r := newReceiver(t)
+	s := newTestSender(t, t.TempDir(), 0, Target{URL: r.URL, Secret: "s3cret"})
+
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Flush(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 1 {
+		t.Fatalf("sent %d deliveries, want 1", result.Sent)
+	}
+
+	req, body := r.request(0)
+	if !Verify("s3cret", body, req.Header.Get(SignatureHeader)) {
+		t.Errorf("signature %q does not match the body", req.Header.Get(SignatureHeader))
+	}
+	if Verify("other", body, req.Header.Get(SignatureHeader)) {
+		t.Error("signature matches with the wrong secret")
+	}
+	if got := req.Header.Get(EventHeader); got != SessionCompleted {
+		t.Errorf("event header is %q, want %q", got, SessionCompleted)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("content type is %q, want application/json", got)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != req.Header.Get(DeliveryHeader) {
+		t.Errorf("payload id %q differs from delivery header %q", payload.ID, req.Header.Get(DeliveryHeader))
+	}
+	if payload.Event != SessionCompleted || payload.Data.Task.Title != "write tests" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	if left := pending(t, s); len(left) != 0 {
+		t.Errorf("%d deliveries left in the outbox after sending", len(left))
+	}
+}
+
+func TestFlushLeavesUnsignedWithoutSecret(t *testing.T) {
+	r := newReceiver(t)
+	s := newTestSender(t, t.TempDir(), 0, Target{URL: r.URL})
+
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	if req, _ := r.request(0); req.Header.Get(SignatureHeader) != "" {
+		t.Errorf("signature header is %q without a secret", req.Header.Get(SignatureHeader))
+	}
+}
+
+func TestEnqueueFiltersEvents(t *testing.T) {
+	s := newTestSender(t, t.TempDir(), 0,
+		Target{URL: "http://127.0.0.1:1/all"},
+		Target{URL: "http://127.0.0.1:1/tasks", Events: []string{TaskAdded}},
+	)
+
+	if err := s.Enqueue(events.Event{Type: events.Tick}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := pending(t, s)
+	if len(deliveries) != 1 || deliveries[0].URL != "http://127.0.0.1:1/all" {
+		t.Fatalf("queued %+v, want one delivery to the target taking every event", deliveries)
+	}
+}
+
+func TestFlushRetriesWithBackoff(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	s := newTestSender(t, t.TempDir(), 0, Target{URL: r.URL, Secret: "s3cret"})
+
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Flush(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 0 || result.Retried != 1 || result.Pending != 1 {
+		t.Fatalf("first flush %+v, want one retry pending", result)
+	}
+
+	deliveries := pending(t, s)
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries in the outbox, want 1", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Attempts != 1 || d.LastError == "" {
+		t.Errorf("delivery after a failure %+v, want one attempt and its error", d)
+	}
+	if !d.NextAttempt.After(time.Now()) || !result.Next.Equal(d.NextAttempt) {
+		t.Errorf("next attempt %s is not scheduled ahead, result says %s", d.NextAttempt, result.Next)
+	}
+
+	// not due yet, so nothing is sent
+	result, err = s.Flush(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.received() != 1 || result.Pending != 1 {
+		t.Fatalf("flush before the backoff sent %d requests, result %+v", r.received(), result)
+	}
+
+	if _, err := s.Flush(true); err != nil {
+		t.Fatal(err)
+	}
+	again := pending(t, s)
+	if len(again) != 1 || again[0].Attempts != 2 {
+		t.Fatalf("after a second failure the outbox holds %+v", again)
+	}
+	if !again[0].NextAttempt.After(d.NextAttempt) {
+		t.Errorf("next attempt %s is not later than the first retry's %s", again[0].NextAttempt, d.NextAttempt)
+	}
+
+	result, err = s.Flush(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 1 || len(pending(t, s)) != 0 {
+		t.Fatalf("third flush %+v, want the delivery sent", result)
+	}
+
+	// every attempt is signed over the same body
+	_, first := r.request(0)
+	for i := 0; i < r.received(); i++ {
+		req, body := r.request(i)
+		if string(body) != string(first) || !Verify("s3cret", body, req.Header.Get(SignatureHeader)) {
+			t.Errorf("attempt %d sent a different or badly signed body", i+1)
+		}
+	}
+}
+
+func TestFlushGivesUpAfterMaxAttempts(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	dir := t.TempDir()
+	s := newTestSender(t, dir, 2, Target{URL: r.URL})
+
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Flush(true); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Flush(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || result.Pending != 0 {
+		t.Fatalf("flush after the last attempt %+v, want it failed", result)
+	}
+	if left := pending(t, s); len(left) != 0 {
+		t.Errorf("%d failed deliveries still queued", len(left))
+	}
+
+	failed, _, err := NewOutbox(filepath.Join(dir, failedDir)).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Attempts != 2 {
+		t.Errorf("failed directory holds %+v, want the delivery after 2 attempts", failed)
+	}
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	dir := t.TempDir()
+
+	first := newTestSender(t, dir, 0, Target{URL: r.URL})
+	if err := first.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	queued := pending(t, first)
+
+	// a later run opens the same outbox and picks the delivery up
+	second := newTestSender(t, dir, 0, Target{URL: r.URL})
+	reopened := pending(t, second)
+	if len(reopened) != 1 || reopened[0].ID != queued[0].ID || reopened[0].Attempts != 1 {
+		t.Fatalf("reopened outbox holds %+v, want %+v", reopened, queued)
+	}
+	if string(reopened[0].Body) != string(queued[0].Body) {
+		t.Error("the queued body changed on disk")
+	}
+
+	result, err := second.Flush(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 1 || r.received() != 2 {
+		t.Fatalf("flush after reopening %+v with %d requests, want it sent", result, r.received())
+	}
+}
+
+func TestFlushFailsRemovedTargets(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestSender(t, dir, 0, Target{URL: "http://127.0.0.1:1/gone"})
+	if err := first.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newReceiver(t)
+	second := newTestSender(t, dir, 0, Target{URL: r.URL})
+	result, err := second.Flush(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || r.received() != 0 {
+		t.Errorf("flush %+v sent %d requests, want the delivery failed unsent", result, r.received())
+	}
+}
+
+func TestWatchQueuesAndDeliverSends(t *testing.T) {
+	r := newReceiver(t)
+	s := newTestSender(t, t.TempDir(), 0, Target{URL: r.URL})
+	bus := events.NewBus()
+
+	stopWatching := s.Watch(bus)
+	bus.Publish(events.Event{Type: events.Tick})
+	bus.Publish(completed())
+	stopWatching()
+
+	if r.received() != 0 {
+		t.Fatal("watching alone delivered")
+	}
+	if got := pending(t, s); len(got) != 1 {
+		t.Fatalf("%d deliveries queued, want 1", len(got))
+	}
+
+	stopDelivering := s.Deliver()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.received() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stopDelivering()
+
+	if r.received() != 1 || len(pending(t, s)) != 0 {
+		t.Errorf("deliver sent %d requests and left %d queued", r.received(), len(pending(t, s)))
+	}
+}
+
+func TestOutboxIsCreatedOnFirstDelivery(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	s := newTestSender(t, dir, 0)
+
+	if got := pending(t, s); len(got) != 0 {
+		t.Fatalf("a missing outbox lists %+v", got)
+	}
+	if _, err := s.Flush(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the outbox was created without anything to queue (%v)", err)
+	}
+
+	r := newReceiver(t)
+	s = newTestSender(t, dir, 0, Target{URL: r.URL})
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+	if got := pending(t, s); len(got) != 1 {
+		t.Errorf("%d deliveries queued, want 1", len(got))
+	}
+}
+
+func TestFlushMovesUnreadableFilesAside(t *testing.T) {
+	r := newReceiver(t)
+	dir := t.TempDir()
+	s := newTestSender(t, dir, 0, Target{URL: r.URL})
+
+	if err := os.WriteFile(filepath.Join(dir, "0-broken.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Enqueue(completed()); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Flush(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 1 || len(result.Unreadable) != 1 || result.Unreadable[0] != "0-broken.json" {
+		t.Fatalf("flush %+v, want the delivery sent and the broken file reported", result)
+	}
+	if _, err := os.Stat(filepath.Join(s.Outbox().FailedDir(), "0-broken.json")); err != nil {
+		t.Errorf("the broken file was not moved aside (%s)", err)
+	}
+
+	// the next flush no longer sees it
+	result, err = s.Flush(false)
+	if err != nil || len(result.Unreadable) != 0 {
+		t.Errorf("second flush %+v, %v", result, err)
+	}
+}